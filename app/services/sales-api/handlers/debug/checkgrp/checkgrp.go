@@ -3,45 +3,47 @@ package checkgrp
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 	"os"
 	"time"
 
-	"github.com/jmoiron/sqlx"
-	"github.com/rdforte/go-service/business/sys/database"
+	"github.com/rdforte/go-service/app/services/sales-api/handlers/debug/debugweb"
 	"go.uber.org/zap"
 )
 
 // Handlers manages the set of check endpoints.
 type Handlers struct {
-	Build string
-	Log   *zap.SugaredLogger
-	DB    *sqlx.DB
+	Build  string
+	Log    *zap.SugaredLogger
+	Checks *Registry
 }
 
-// Readiness checks if the database is ready and if not will return a 500 status.
+// Readiness runs the registered checks and returns a 503 status if any of them are not ready.
+// The status and latency of each check is listed so the failing dependency can be identified.
 // Do not respond by just returning an error because further up in the call stack
 // it will interpret that as a non-trusted error.
 func (h Handlers) Readiness(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	results, ready := h.Checks.Run(ctx)
+
 	status := "ok"
 	statusCode := http.StatusOK
-
-	if err := database.StatusCheck(ctx, h.DB); err != nil {
-		status = "db not ready"
-		statusCode = http.StatusInternalServerError
+	if !ready {
+		status = "not ready"
+		statusCode = http.StatusServiceUnavailable
 	}
 
 	data := struct {
-		Status string `json:"status"`
+		Status string   `json:"status"`
+		Checks []Result `json:"checks"`
 	}{
 		Status: status,
+		Checks: results,
 	}
 
-	if err := response(w, statusCode, data); err != nil {
+	if err := debugweb.Respond(w, statusCode, data); err != nil {
 		h.Log.Errorw("readiness", "ERROR", err)
 	}
 
@@ -76,31 +78,9 @@ func (h Handlers) Liveness(w http.ResponseWriter, r *http.Request) {
 	}
 
 	statusCode := http.StatusOK
-	if err := response(w, statusCode, data); err != nil {
+	if err := debugweb.Respond(w, statusCode, data); err != nil {
 		h.Log.Errorw("liveness", "ERROR", err)
 	}
 
 	h.Log.Infow("liveness", "statusCode", statusCode, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
 }
-
-// response is a convenient function for marshaling the data, setting the correct headers and the writing the json to http.ResponseWriter
-func response(w http.ResponseWriter, statusCode int, data interface{}) error {
-	// Convert the response value to JSON.
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-
-	// Set the content type headers once we know marshaling is successful.
-	w.Header().Set("Content-Type", "application/json")
-
-	// Write the status code to the response
-	w.WriteHeader(statusCode)
-
-	// Send the result back to the client.
-	if _, err := w.Write(jsonData); err != nil {
-		return err
-	}
-
-	return nil
-}
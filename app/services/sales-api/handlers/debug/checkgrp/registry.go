@@ -0,0 +1,110 @@
+package checkgrp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check reports whether a dependency of the service is ready. It returns a non nil
+// error when the dependency is not ready.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of running a single check.
+type Result struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// check is a named Check registered with the Registry.
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      Check
+}
+
+// Registry maintains the set of checks which decide the readiness of the service. Results
+// are cached for the ttl so frequent readiness probes do not hammer the dependencies.
+type Registry struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	checks   []check
+	results  []Result
+	ready    bool
+	cachedAt time.Time
+}
+
+// NewRegistry constructs a Registry which caches results for the ttl.
+func NewRegistry(ttl time.Duration) *Registry {
+	return &Registry{
+		ttl: ttl,
+	}
+}
+
+// Register adds a named check to the Registry. Each run of the check is bounded by the
+// timeout, a zero timeout relies on the deadline of the request.
+func (r *Registry) Register(name string, timeout time.Duration, fn Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, check{name: name, timeout: timeout, fn: fn})
+	r.cachedAt = time.Time{}
+}
+
+// Run runs every check concurrently and reports whether they all passed. Callers arriving
+// while the checks are running wait for and share the same results.
+func (r *Registry) Run(ctx context.Context) ([]Result, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.cachedAt.IsZero() && time.Since(r.cachedAt) < r.ttl {
+		return r.results, r.ready
+	}
+
+	results := make([]Result, len(r.checks))
+
+	var wg sync.WaitGroup
+	wg.Add(len(r.checks))
+	for i, c := range r.checks {
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, result := range results {
+		if result.Error != "" {
+			ready = false
+		}
+	}
+
+	r.results, r.ready, r.cachedAt = results, ready, time.Now()
+	return results, ready
+}
+
+// run runs the check within its timeout and records how long it took.
+func (c check) run(ctx context.Context) Result {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := c.fn(ctx)
+
+	result := Result{
+		Name:    c.name,
+		Status:  "ok",
+		Latency: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = "not ready"
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,51 @@
+package checkgrp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rdforte/go-service/app/services/sales-api/handlers/debug/checkgrp"
+	"github.com/rdforte/go-service/foundation/logger"
+)
+
+func TestRegistry(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Running the readiness checks")
+	{
+		tl.It("should report each check and cache the results")
+		{
+			var runs int
+			checks := checkgrp.NewRegistry(time.Minute)
+
+			checks.Register("database", 0, func(ctx context.Context) error {
+				runs++
+				return nil
+			})
+			checks.Register("downstream", time.Millisecond, func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+
+			results, ready := checks.Run(context.Background())
+			if ready {
+				tl.Failed("Should not be ready when a check fails", errors.New("ready"))
+			}
+			tl.Success("Should not be ready when a check fails")
+
+			if len(results) != 2 || results[0].Status != "ok" || results[1].Error != context.DeadlineExceeded.Error() {
+				tl.Failed("Should report the status of each check", fmt.Errorf("results%+v", results))
+			}
+			tl.Success("Should report the status of each check")
+
+			checks.Run(context.Background())
+			if runs != 1 {
+				tl.Failed("Should cache the results", fmt.Errorf("runs[%d]", runs))
+			}
+			tl.Success("Should cache the results")
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package debugweb provides the support shared by the debug handler groups. The debug routes
+// are registered on a standard library mux so they can't use the web framework to respond.
+package debugweb
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Respond is a convenient function for marshaling the data, setting the correct headers and the writing the json to http.ResponseWriter
+func Respond(w http.ResponseWriter, statusCode int, data interface{}) error {
+	// Convert the response value to JSON.
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	// Set the content type headers once we know marshaling is successful.
+	w.Header().Set("Content-Type", "application/json")
+
+	// Write the status code to the response
+	w.WriteHeader(statusCode)
+
+	// Send the result back to the client.
+	if _, err := w.Write(jsonData); err != nil {
+		return err
+	}
+
+	return nil
+}
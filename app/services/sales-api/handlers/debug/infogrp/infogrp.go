@@ -0,0 +1,101 @@
+// Package infogrp maintains the group of handlers for reporting what is deployed.
+package infogrp
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/rdforte/go-service/app/services/sales-api/handlers/debug/debugweb"
+	"go.uber.org/zap"
+)
+
+// Handlers manages the set of info endpoints.
+type Handlers struct {
+	Build     string
+	Log       *zap.SugaredLogger
+	StartTime time.Time
+
+	// Config is the effective configuration of the service. Secrets must be masked before
+	// it is provided.
+	Config interface{}
+}
+
+// module describes a module which is compiled into the binary.
+type module struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"`
+}
+
+// vcs describes the version control state the binary was built from.
+type vcs struct {
+	Revision string `json:"revision,omitempty"`
+	Time     string `json:"time,omitempty"`
+	Modified bool   `json:"modified"`
+}
+
+// Info returns the build and runtime information of the service so we can verify exactly what
+// is deployed. The build information is only available when the binary is built with module support.
+func (h Handlers) Info(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	data := struct {
+		Build      string      `json:"build"`
+		GoVersion  string      `json:"goVersion"`
+		GOMAXPROCS int         `json:"gomaxprocs"`
+		NumCPU     int         `json:"numCPU"`
+		StartTime  time.Time   `json:"startTime"`
+		Uptime     string      `json:"uptime"`
+		Main       module      `json:"main"`
+		VCS        vcs         `json:"vcs"`
+		Deps       []module    `json:"deps"`
+		Config     interface{} `json:"config"`
+	}{
+		Build:      h.Build,
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		StartTime:  h.StartTime.UTC(),
+		Uptime:     now.Sub(h.StartTime).Round(time.Second).String(),
+		Config:     h.Config,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		data.Main = toModule(&bi.Main)
+		for _, dep := range bi.Deps {
+			data.Deps = append(data.Deps, toModule(dep))
+		}
+
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				data.VCS.Revision = setting.Value
+			case "vcs.time":
+				data.VCS.Time = setting.Value
+			case "vcs.modified":
+				data.VCS.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	statusCode := http.StatusOK
+	if err := debugweb.Respond(w, statusCode, data); err != nil {
+		h.Log.Errorw("info", "ERROR", err)
+	}
+
+	h.Log.Infow("info", "statusCode", statusCode, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
+}
+
+// toModule converts the build information of a module.
+func toModule(m *debug.Module) module {
+	mod := module{
+		Path:    m.Path,
+		Version: m.Version,
+	}
+	if m.Replace != nil {
+		mod.Replace = m.Replace.Path + " " + m.Replace.Version
+	}
+	return mod
+}
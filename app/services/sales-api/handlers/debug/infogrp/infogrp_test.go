@@ -0,0 +1,64 @@
+package infogrp_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/rdforte/go-service/app/services/sales-api/handlers/debug/infogrp"
+	"github.com/rdforte/go-service/foundation/logger"
+	"go.uber.org/zap"
+)
+
+func TestInfo(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Reporting what is deployed")
+	{
+		tl.It("should report the build, runtime and config of the service")
+		{
+			h := infogrp.Handlers{
+				Build:     "1.2.3",
+				Log:       zap.NewNop().Sugar(),
+				StartTime: time.Now().Add(-time.Hour),
+				Config:    map[string]string{"apiHost": ":3000", "secret": "xxxxxx"},
+			}
+
+			w := httptest.NewRecorder()
+			h.Info(w, httptest.NewRequest(http.MethodGet, "/debug/info", nil))
+
+			var info struct {
+				Build      string            `json:"build"`
+				GoVersion  string            `json:"goVersion"`
+				GOMAXPROCS int               `json:"gomaxprocs"`
+				NumCPU     int               `json:"numCPU"`
+				StartTime  time.Time         `json:"startTime"`
+				Uptime     string            `json:"uptime"`
+				Config     map[string]string `json:"config"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&info); err != nil || w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+				tl.Failed("Should respond with the info", fmt.Errorf("status[%d] content-type[%s] err[%v]", w.Code, w.Header().Get("Content-Type"), err))
+			}
+			tl.Success("Should respond with the info")
+
+			if info.Build != "1.2.3" || info.GoVersion != runtime.Version() || info.GOMAXPROCS != runtime.GOMAXPROCS(0) || info.NumCPU != runtime.NumCPU() {
+				tl.Failed("Should report the build and runtime", fmt.Errorf("info[%+v]", info))
+			}
+			tl.Success("Should report the build and runtime")
+
+			if !info.StartTime.Equal(h.StartTime) || info.StartTime.Location() != time.UTC || info.Uptime != "1h0m0s" {
+				tl.Failed("Should report the start time and uptime", fmt.Errorf("start[%v] uptime[%s]", info.StartTime, info.Uptime))
+			}
+			tl.Success("Should report the start time and uptime")
+
+			if info.Config["apiHost"] != ":3000" || info.Config["secret"] != "xxxxxx" {
+				tl.Failed("Should report the config as provided", fmt.Errorf("config%v", info.Config))
+			}
+			tl.Success("Should report the config as provided")
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package loggrp maintains the group of handlers for controlling the log levels at runtime.
+package loggrp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rdforte/go-service/app/services/sales-api/handlers/debug/debugweb"
+	"github.com/rdforte/go-service/foundation/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Handlers manages the set of log level endpoints.
+type Handlers struct {
+	Log    *zap.SugaredLogger
+	Levels *logger.Levels
+}
+
+// LogLevel returns the level of every logger component on GET and sets the level of a
+// component on PUT. A PUT may provide a ttl (ie: "10m") after which the component reverts
+// to the default level so debug logs are not left on by accident.
+func (h Handlers) LogLevel(w http.ResponseWriter, r *http.Request) {
+	statusCode := http.StatusOK
+
+	switch r.Method {
+	case http.MethodGet:
+
+	case http.MethodPut:
+		if err := h.setLevel(r); err != nil {
+			statusCode = http.StatusBadRequest
+			h.respond(w, r, statusCode, struct {
+				Error string `json:"error"`
+			}{
+				Error: err.Error(),
+			})
+			return
+		}
+
+	default:
+		statusCode = http.StatusMethodNotAllowed
+		w.Header().Set("Allow", "GET, PUT")
+		h.respond(w, r, statusCode, struct {
+			Error string `json:"error"`
+		}{
+			Error: http.StatusText(statusCode),
+		})
+		return
+	}
+
+	h.respond(w, r, statusCode, struct {
+		Levels map[string]string `json:"levels"`
+	}{
+		Levels: h.Levels.Levels(),
+	})
+}
+
+// setLevel decodes the request and sets the level of the component.
+func (h Handlers) setLevel(r *http.Request) error {
+	var req struct {
+		Component string `json:"component"`
+		Level     string `json:"level"`
+		TTL       string `json:"ttl"`
+	}
+
+	if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<10)).Decode(&req); err != nil {
+		return err
+	}
+
+	if req.Component == "" {
+		req.Component = logger.Root
+	}
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+		return err
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			return err
+		}
+	}
+
+	if err := h.Levels.SetLevel(req.Component, level, ttl); err != nil {
+		return err
+	}
+
+	h.Log.Infow("loglevel", "component", req.Component, "level", level, "ttl", ttl)
+	return nil
+}
+
+// respond writes the data as json logging any failure.
+func (h Handlers) respond(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	if err := debugweb.Respond(w, statusCode, data); err != nil {
+		h.Log.Errorw("loglevel", "ERROR", err)
+	}
+
+	h.Log.Infow("loglevel", "statusCode", statusCode, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
+}
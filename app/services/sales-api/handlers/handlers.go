@@ -3,17 +3,28 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"expvar"
+	"fmt"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/rdforte/go-service/app/services/sales-api/handlers/debug/checkgrp"
+	"github.com/rdforte/go-service/app/services/sales-api/handlers/debug/infogrp"
+	"github.com/rdforte/go-service/app/services/sales-api/handlers/debug/loggrp"
 	"github.com/rdforte/go-service/app/services/sales-api/handlers/v1/userRoutes"
+	"github.com/rdforte/go-service/business/core/idempotency"
 	"github.com/rdforte/go-service/business/core/user"
+	"github.com/rdforte/go-service/business/data/schema"
 	"github.com/rdforte/go-service/business/sys/auth"
+	"github.com/rdforte/go-service/business/sys/database"
+	"github.com/rdforte/go-service/business/sys/metrics"
 	"github.com/rdforte/go-service/business/web/mid"
+	"github.com/rdforte/go-service/foundation/logger"
 	"github.com/rdforte/go-service/foundation/web"
 	"go.uber.org/zap"
 )
@@ -36,23 +47,78 @@ func debugStandardLibraryMux() *http.ServeMux {
 	return mux
 }
 
+// DebugMuxConfig contains all the mandatory systems required by the debug handlers.
+type DebugMuxConfig struct {
+	Build string
+	Log   *zap.SugaredLogger
+	DB    *sqlx.DB
+	Auth  *auth.Auth
+
+	// Ready reports whether the service has started and is not shutting down.
+	Ready func() bool
+
+	// Levels controls the log levels of the service at runtime.
+	Levels *logger.Levels
+
+	// StartTime and Config are reported by the info endpoint. Secrets in the Config
+	// must be masked.
+	StartTime time.Time
+	Config    interface{}
+}
+
 // DebugMux registers all the debug standard library routes and then custom debug
 // application routes for the service. This bypasses the use of the DefaultServerMux.
 // Using the DefaultServerMux would be a security risk since a dependency could inject
 // a handler into our service without us knowing it.
-func DebugMux(build string, log *zap.SugaredLogger, db *sqlx.DB) http.Handler {
+func DebugMux(cfg DebugMuxConfig) http.Handler {
 	mux := debugStandardLibraryMux()
 
+	// Register the checks deciding the readiness of the service. Downstream services the
+	// service depends on are registered here as well.
+	checks := checkgrp.NewRegistry(time.Second)
+
+	checks.Register("service", 0, func(ctx context.Context) error {
+		if !cfg.Ready() {
+			return errors.New("service is starting or shutting down")
+		}
+		return nil
+	})
+	checks.Register("database", time.Second, func(ctx context.Context) error {
+		return database.StatusCheck(ctx, cfg.DB)
+	})
+	checks.Register("migrations", time.Second, func(ctx context.Context) error {
+		return schema.StatusCheck(ctx, cfg.DB)
+	})
+	checks.Register("keystore", time.Second, cfg.Auth.StatusCheck)
+
 	// Register debug check endpoints.
 	cgh := checkgrp.Handlers{
-		Build: build,
-		Log:   log,
-		DB:    db,
+		Build:  cfg.Build,
+		Log:    cfg.Log,
+		Checks: checks,
 	}
 
 	mux.HandleFunc("/debug/readiness", cgh.Readiness)
 	mux.HandleFunc("/debug/liveness", cgh.Liveness)
 
+	// Register debug info endpoints.
+	igh := infogrp.Handlers{
+		Build:     cfg.Build,
+		Log:       cfg.Log,
+		StartTime: cfg.StartTime,
+		Config:    cfg.Config,
+	}
+
+	mux.HandleFunc("/debug/info", igh.Info)
+
+	// Register debug log level endpoints.
+	lgh := loggrp.Handlers{
+		Log:    cfg.Log,
+		Levels: cfg.Levels,
+	}
+
+	mux.HandleFunc("/debug/loglevel", lgh.LogLevel)
+
 	return mux
 }
 
@@ -60,36 +126,100 @@ func DebugMux(build string, log *zap.SugaredLogger, db *sqlx.DB) http.Handler {
 type APIMuxConfig struct {
 	Shutdown chan os.Signal
 	Log      *zap.SugaredLogger
+	Levels   *logger.Levels
 	Auth     *auth.Auth
 	DB       *sqlx.DB
+
+	// AccessLog enables a single line access log per request in place of the request
+	// started and completed logs.
+	AccessLog *mid.AccessLogConfig
+
+	// CORS is the policy for cross origin requests from our browser frontend. Cross origin
+	// requests are not allowed when nil.
+	CORS *mid.CORSPolicy
+
+	// ShutdownPolicy sets when unhandled errors shutdown the service. OnError is provided by APIMux.
+	ShutdownPolicy web.ShutdownPolicy
+
+	// IdempotencyTTL is how long the responses of requests made with an Idempotency-Key are replayed.
+	IdempotencyTTL time.Duration
+
+	// IdempotencySecret keys the HMAC of the stored idempotency keys.
+	IdempotencySecret []byte
+
+	// CookieSecure and CookieSameSite set the attributes of the cookie carrying the auth token.
+	// SameSite defaults to Lax and the cookie is always secure for requests made over TLS.
+	CookieSecure   bool
+	CookieSameSite http.SameSite
 }
 
 // APIMux constructs an http.Handler with all application routes defined.
-func APIMux(cfg APIMuxConfig) *web.App {
+func APIMux(cfg APIMuxConfig) (*web.App, error) {
+
+	// Errors escaping the middleware chain are logged and counted rather than shutting down the
+	// service. The metrics are not in the context at this point so they are added to it.
+	policy := cfg.ShutdownPolicy
+	policy.OnError = func(ctx context.Context, err error) {
+		cfg.Log.Errorw("unhandled error", "traceid", web.GetTraceID(ctx), "ERROR", err)
+		metrics.AddUnhandled(metrics.Set(ctx))
+	}
 
 	// set up the web app with app specific middleware
 	// Panics must always be at the end so that it is the first middleware to be called around the
 	// handler in case there is a panic within the handler we can handle this.
-	r := web.NewApp(cfg.Shutdown,
-		mid.Logger(cfg.Log),
-		mid.Errors(cfg.Log),
+	// CORS runs before any other middleware writes a response so every response carries the
+	// CORS headers, and Compress wraps Errors so error responses are compressed too.
+	requestLog := mid.Logger(cfg.Log)
+	if cfg.AccessLog != nil {
+		requestLog = mid.AccessLog(cfg.Log, *cfg.AccessLog)
+	}
+
+	mw := []web.Middleware{requestLog}
+	if cfg.CORS != nil {
+		cors, err := mid.CORS(*cfg.CORS, nil)
+		if err != nil {
+			return nil, fmt.Errorf("cors: %w", err)
+		}
+		mw = append(mw, cors)
+	}
+	mw = append(mw,
+		mid.Compress(mid.CompressConfig{}),
+		mid.Errors(cfg.Log, "v2"),
 		mid.Metrics(),
 		mid.Panics(),
 	)
 
-	// Load the routes for the different versions of the API.
-	v1(r, cfg)
+	r := web.NewApp(cfg.Shutdown, mw, web.WithShutdownPolicy(policy))
 
-	return r
-}
+	// The cores and the idempotency store are built once and shared by every version of the API.
+	usr := user.NewCore(cfg.Levels.Named(cfg.Log, "user"), cfg.DB)
+	idem := idempotent(cfg)
 
-func v1(app *web.App, cfg APIMuxConfig) {
-	const version = "v1"
+	// Load the routes for the different versions of the API. The user routes are unchanged in v2,
+	// which only responds to errors with problem+json.
+	for _, version := range []string{"v1", "v2"} {
+		g := r.Group(version)
 
-	// Register User Routes
-	userRoutes.CreateUserV1Routes(app,
-		user.NewCore(cfg.Log, cfg.DB),
-		cfg.Auth,
-	)
+		// Register User Routes
+		userRoutes.Routes(g, usr, cfg.Auth, idem, cookie(cfg))
+	}
+
+	return r, nil
+}
+
+// cookie returns the attributes of the cookie carrying the auth token.
+func cookie(cfg APIMuxConfig) userRoutes.CookieConfig {
+	return userRoutes.CookieConfig{
+		Secure:   cfg.CookieSecure,
+		SameSite: cfg.CookieSameSite,
+	}
+}
 
+// idempotent returns the middleware replaying the responses of requests made with an Idempotency-Key.
+func idempotent(cfg APIMuxConfig) mid.Idempotent {
+	log := cfg.Levels.Named(cfg.Log, "idempotency")
+	return mid.Idempotency(log, idempotency.NewCore(log, cfg.DB), mid.IdempotencyConfig{
+		TTL:    cfg.IdempotencyTTL,
+		Secret: cfg.IdempotencySecret,
+	})
 }
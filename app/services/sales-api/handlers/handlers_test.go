@@ -0,0 +1,57 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/rdforte/go-service/app/services/sales-api/handlers"
+	"github.com/rdforte/go-service/business/sys/validate"
+	"github.com/rdforte/go-service/foundation/logger"
+	"go.uber.org/zap"
+)
+
+func TestAPIMuxVersions(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	// The requests are rejected before the database is used so none is needed.
+	app, err := handlers.APIMux(handlers.APIMuxConfig{
+		Shutdown: make(chan os.Signal, 1),
+		Log:      zap.NewNop().Sugar(),
+	})
+	if err != nil {
+		t.Fatalf("constructing api mux: %v", err)
+	}
+
+	tl.Describe("Responding to errors in the format of the API version")
+	{
+		tl.It("should respond to v1 with the legacy shape")
+		{
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/user", nil))
+
+			var er validate.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&er); err != nil || w.Code != http.StatusUnauthorized ||
+				w.Header().Get("Content-Type") != "application/json" || er.Code != "UNAUTHORIZED" || er.Error == "" {
+				tl.Failed("Should respond with the legacy shape", fmt.Errorf("status[%d] content-type[%s] body[%+v] err[%v]", w.Code, w.Header().Get("Content-Type"), er, err))
+			}
+			tl.Success("Should respond with the legacy shape")
+		}
+
+		tl.It("should respond to v2 with problem+json")
+		{
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/user", nil))
+
+			var pd validate.ProblemDetail
+			if err := json.NewDecoder(w.Body).Decode(&pd); err != nil || w.Code != http.StatusUnauthorized ||
+				w.Header().Get("Content-Type") != validate.ProblemContentType || pd.Status != http.StatusUnauthorized || pd.Code != "UNAUTHORIZED" {
+				tl.Failed("Should respond with problem+json", fmt.Errorf("status[%d] content-type[%s] body[%+v] err[%v]", w.Code, w.Header().Get("Content-Type"), pd, err))
+			}
+			tl.Success("Should respond with problem+json")
+		}
+	}
+}
@@ -2,11 +2,9 @@ package userRoutes
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
 
-	"github.com/rdforte/go-service/business/core/user"
 	"github.com/rdforte/go-service/business/sys/auth"
 	"github.com/rdforte/go-service/business/sys/validate"
 	"github.com/rdforte/go-service/foundation/web"
@@ -21,15 +19,8 @@ func (h userHandler) deleteUser(ctx context.Context, w http.ResponseWriter, r *h
 
 	userID := claims.Subject
 
-	if err := h.user.Delete(ctx, userID); err != nil {
-		switch {
-		case errors.Is(err, user.ErrInvalidID):
-			return validate.NewRequestError(err, http.StatusBadRequest)
-		case errors.Is(err, user.ErrNotFound):
-			return validate.NewRequestError(err, http.StatusNotFound)
-		default:
-			return fmt.Errorf("ID[%s]: %w", userID, err)
-		}
+	if err := h.user.Delete(ctx, userID, web.IfMatch(r)...); err != nil {
+		return fmt.Errorf("ID[%s]: %w", userID, err)
 	}
 
 	return web.RespondOk(ctx, w)
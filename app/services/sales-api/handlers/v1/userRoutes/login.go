@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/rdforte/go-service/business/core/user"
 	"github.com/rdforte/go-service/business/sys/validate"
 	"github.com/rdforte/go-service/foundation/web"
 )
@@ -14,6 +13,35 @@ import (
 // cookieKey is the key for the token when set in the cookies.
 var cookieKey = "xra789klate"
 
+// CookieConfig sets the attributes of the cookie carrying the token.
+type CookieConfig struct {
+
+	// Secure only sends the cookie over TLS. The cookie is always secure when the request was
+	// made over TLS.
+	Secure bool
+
+	// SameSite defaults to Lax. None allows a browser frontend served from a different site to
+	// send the cookie with its CORS requests, which browsers only allow for a secure cookie.
+	SameSite http.SameSite
+}
+
+// tokenCookie returns the cookie carrying the token. HttpOnly keeps the token out of reach of scripts.
+func (h userHandler) tokenCookie(r *http.Request, tok string) *http.Cookie {
+	sameSite := h.cookie.SameSite
+	if sameSite == 0 {
+		sameSite = http.SameSiteLaxMode
+	}
+
+	return &http.Cookie{
+		Name:     cookieKey,
+		Value:    tok,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   h.cookie.Secure || r.TLS != nil,
+		SameSite: sameSite,
+	}
+}
+
 func (h userHandler) login(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	v, err := web.GetValues(ctx)
 	if err != nil {
@@ -28,14 +56,7 @@ func (h userHandler) login(ctx context.Context, w http.ResponseWriter, r *http.R
 
 	claims, err := h.user.Authenticate(ctx, v.Now, email, pass)
 	if err != nil {
-		switch {
-		case errors.Is(err, user.ErrNotFound):
-			return validate.NewRequestError(err, http.StatusNotFound)
-		case errors.Is(err, user.ErrAuthenticationFailure):
-			return validate.NewRequestError(err, http.StatusUnauthorized)
-		default:
-			return fmt.Errorf("authenticating: %w", err)
-		}
+		return fmt.Errorf("authenticating: %w", err)
 	}
 
 	tok, err := h.auth.GenerateToken(claims)
@@ -43,10 +64,7 @@ func (h userHandler) login(ctx context.Context, w http.ResponseWriter, r *http.R
 		return fmt.Errorf("generating token: %w", err)
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:  cookieKey,
-		Value: tok,
-	})
+	http.SetCookie(w, h.tokenCookie(r, tok))
 
 	return web.RespondOk(ctx, w)
 }
@@ -0,0 +1,68 @@
+package userRoutes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rdforte/go-service/business/core/user"
+	"github.com/rdforte/go-service/business/sys/paging"
+	"github.com/rdforte/go-service/business/sys/validate"
+	"github.com/rdforte/go-service/foundation/web"
+)
+
+// queryUsers returns a page of the users matching the filter in the query string.
+func (h userHandler) queryUsers(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	values := r.URL.Query()
+
+	req, err := paging.Parse(values, user.OrderByFields, user.DefaultOrderBy)
+	if err != nil {
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	filter, err := parseFilter(values)
+	if err != nil {
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	page, err := h.user.Query(ctx, filter, req)
+	if err != nil {
+		return fmt.Errorf("querying users: %w", err)
+	}
+
+	return web.Respond(ctx, w, page, http.StatusOK)
+}
+
+// parseFilter reads the user filter from the query string. Dates are in RFC 3339 format.
+func parseFilter(values url.Values) (user.QueryFilter, error) {
+	var filter user.QueryFilter
+
+	if v := values.Get("name"); v != "" {
+		filter.Name = &v
+	}
+	if v := values.Get("email"); v != "" {
+		filter.Email = &v
+	}
+	if v := values.Get("role"); v != "" {
+		filter.Role = &v
+	}
+
+	for param, dst := range map[string]**time.Time{
+		"start_created_date": &filter.StartCreatedDate,
+		"end_created_date":   &filter.EndCreatedDate,
+	} {
+		v := values.Get(param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return user.QueryFilter{}, fmt.Errorf("%s must be an RFC 3339 date: %w", param, err)
+		}
+		*dst = &t
+	}
+
+	return filter, nil
+}
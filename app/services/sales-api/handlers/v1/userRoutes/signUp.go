@@ -9,15 +9,21 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/rdforte/go-service/business/core/user"
 	"github.com/rdforte/go-service/business/sys/auth"
+	"github.com/rdforte/go-service/business/sys/validate"
 	"github.com/rdforte/go-service/foundation/web"
 )
 
 // The fields we expect the client to send when they signup
 type decodeUser struct {
-	Name            string `json:"name"`
-	Email           string `json:"email"`
-	Password        string `json:"password"`
-	PasswordConfirm string `json:"password_confirm"`
+	Name            string `json:"name" validate:"required"`
+	Email           string `json:"email" validate:"required,email"`
+	Password        string `json:"password" validate:"required,password"`
+	PasswordConfirm string `json:"password_confirm" validate:"eqfield=Password"`
+}
+
+// Validate checks the data the client sent is considered clean.
+func (du decodeUser) Validate() error {
+	return validate.Check(du)
 }
 
 // Create adds a new user to the system.
@@ -62,10 +68,36 @@ func (h userHandler) signUp(ctx context.Context, w http.ResponseWriter, r *http.
 		return fmt.Errorf("generating token: %w", err)
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:  cookieKey,
-		Value: tok,
-	})
+	http.SetCookie(w, h.tokenCookie(r, tok))
 
 	return web.RespondOk(ctx, w)
 }
+
+// reissueToken sets a new token when a signup is replayed, since tokens are not stored with the
+// response. The replayed request carries the credentials of the user which are authenticated again.
+func (h userHandler) reissueToken(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var du decodeUser
+
+	if err := web.Decode(r, &du); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	claims, err := h.user.Authenticate(ctx, v.Now, du.Email, du.Password)
+	if err != nil {
+		return fmt.Errorf("authenticating: %w", err)
+	}
+
+	tok, err := h.auth.GenerateToken(claims)
+	if err != nil {
+		return fmt.Errorf("generating token: %w", err)
+	}
+
+	http.SetCookie(w, h.tokenCookie(r, tok))
+
+	return nil
+}
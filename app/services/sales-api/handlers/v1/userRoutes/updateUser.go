@@ -2,7 +2,6 @@ package userRoutes
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
 
@@ -31,15 +30,8 @@ func (h userHandler) updateUser(ctx context.Context, w http.ResponseWriter, r *h
 
 	userID := claims.Subject
 
-	if err := h.user.Update(ctx, userID, upd, v.Now); err != nil {
-		switch {
-		case errors.Is(err, user.ErrInvalidID):
-			return validate.NewRequestError(err, http.StatusBadRequest)
-		case errors.Is(err, user.ErrNotFound):
-			return validate.NewRequestError(err, http.StatusNotFound)
-		default:
-			return fmt.Errorf("ID[%s] User[%+v]: %w", userID, &upd, err)
-		}
+	if err := h.user.Update(ctx, userID, upd, v.Now, web.IfMatch(r)...); err != nil {
+		return fmt.Errorf("ID[%s] User[%+v]: %w", userID, &upd, err)
 	}
 
 	return web.RespondOk(ctx, w)
@@ -9,26 +9,35 @@ import (
 )
 
 type userHandler struct {
-	user user.Core
-	auth *auth.Auth
+	user   user.Core
+	auth   *auth.Auth
+	cookie CookieConfig
 }
 
-// CreateUserV1Routes is a function responsible for setting up all the V1 User routes.
-func CreateUserV1Routes(app *web.App, user user.Core, auth *auth.Auth) {
+// Routes is a function responsible for setting up all the User routes within the group. Versions
+// of the API where the user routes are unchanged share these handlers.
+// Requests made with an Idempotency-Key are handled by the idempotent middleware.
+func Routes(g *web.Group, user user.Core, a *auth.Auth, idempotent mid.Idempotent, cookie CookieConfig) {
 	// Create User Handler
 	usrHandler := userHandler{
 		user,
-		auth,
+		a,
+		cookie,
 	}
 
-	authenticate := mid.Authenticate(auth)
+	authenticate := mid.Authenticate(a)
 
 	// User Routes
-	app.Post("/user/login", "v1", usrHandler.login)
-	app.Post("/user/signup", "v1", usrHandler.signUp)
+	g.Post("/user/login", usrHandler.login)
+	g.Post("/user/signup", usrHandler.signUp, idempotent(usrHandler.reissueToken))
 
 	// User Routes (Authenticated)
-	app.Get("/user", "v1", usrHandler.getUser, authenticate)
-	app.Patch("/user", "v1", usrHandler.updateUser, authenticate)
-	app.Delete("/user", "v1", usrHandler.deleteUser, authenticate)
+	authed := g.Group("", authenticate)
+	authed.Get("/user", usrHandler.getUser)
+	authed.Patch("/user", usrHandler.updateUser)
+	authed.Delete("/user", usrHandler.deleteUser)
+
+	// User Routes (Admin)
+	admin := authed.Group("", mid.Authorize(auth.RoleAdmin))
+	admin.Get("/users", usrHandler.queryUsers)
 }
@@ -2,19 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"expvar"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/rdforte/go-service/app/services/sales-api/handlers"
+	"github.com/rdforte/go-service/business/core/idempotency"
 	"github.com/rdforte/go-service/business/sys/auth"
 	"github.com/rdforte/go-service/business/sys/database"
+	"github.com/rdforte/go-service/business/web/mid"
 	"github.com/rdforte/go-service/foundation/keystore"
+	"github.com/rdforte/go-service/foundation/lifecycle"
 	"github.com/rdforte/go-service/foundation/logger"
+	"github.com/rdforte/go-service/foundation/web"
 	"github.com/spf13/viper"
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/automaxprocs/maxprocs"
@@ -29,7 +35,13 @@ import (
 var build = "develop"
 
 func main() {
-	log, err := logger.New("SALES-API")
+	// The request and access logs are sampled since they are written for every request.
+	log, levels, err := logger.New("SALES-API", logger.WithSampling(logger.Sampling{
+		Messages:   []string{"request started", "request completed", "request"},
+		Tick:       time.Second,
+		First:      100,
+		Thereafter: 100,
+	}))
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -37,13 +49,15 @@ func main() {
 	defer log.Sync()
 
 	// Perform startup and shutdown sequence.
-	if err := run(log); err != nil {
+	if err := run(log, levels); err != nil {
 		log.Errorw("startup", "ERROR", err)
 		os.Exit(1)
 	}
 }
 
-func run(log *zap.SugaredLogger) error {
+func run(log *zap.SugaredLogger, levels *logger.Levels) error {
+	start := time.Now()
+
 	// =========================================================================================================
 	// GOMAXPROCS
 
@@ -62,12 +76,37 @@ func run(log *zap.SugaredLogger) error {
 			Desc string `yaml:"desc"`
 		}
 		Web struct {
-			ReadTimeout     int    `yaml:"readTimeout"`
-			WriteTimeout    int    `yaml:"writeTimeout"`
-			IdleTimeout     int    `yaml:"idleTimeout"`
-			ShutdownTimeout int    `yaml:"shutdownTimeout"`
-			APIHost         string `yaml:"apiHost"`
-			DebugHost       string `yaml:"debugHost"`
+			ReadTimeout        int    `yaml:"readTimeout"`
+			WriteTimeout       int    `yaml:"writeTimeout"`
+			IdleTimeout        int    `yaml:"idleTimeout"`
+			ShutdownTimeout    int    `yaml:"shutdownTimeout"`
+			DrainDelay         int    `yaml:"drainDelay"`
+			IntegrityThreshold int    `yaml:"integrityThreshold"`
+			IntegrityWindow    int    `yaml:"integrityWindow"`
+			APIHost            string `yaml:"apiHost"`
+			DebugHost          string `yaml:"debugHost"`
+		}
+		Idempotency struct {
+			TTL           int    `yaml:"ttl"`
+			PurgeInterval int    `yaml:"purgeInterval"`
+			Secret        string `yaml:"secret"`
+		}
+		AccessLog struct {
+			Enabled       bool     `yaml:"enabled"`
+			SkipPaths     []string `yaml:"skipPaths"`
+			SlowThreshold int      `yaml:"slowThreshold"`
+			Fields        []string `yaml:"fields"`
+		}
+		Cookie struct {
+			Secure   bool   `yaml:"secure"`
+			SameSite string `yaml:"sameSite"`
+		}
+		CORS struct {
+			AllowedOrigins   []string `yaml:"allowedOrigins"`
+			AllowedHeaders   []string `yaml:"allowedHeaders"`
+			ExposedHeaders   []string `yaml:"exposedHeaders"`
+			AllowCredentials bool     `yaml:"allowCredentials"`
+			MaxAge           int      `yaml:"maxAge"`
 		}
 		Auth struct {
 			ActiveKID string `yaml:"activeKID"`
@@ -80,6 +119,9 @@ func run(log *zap.SugaredLogger) error {
 			MaxIdleConns int    `yaml:"maxIdleConns"`
 			MaxOpenConns int    `yaml:"maxOpenConns"`
 			DisableTLS   bool   `yaml:"disableTLS"`
+
+			SlowQueryThreshold int  `yaml:"slowQueryThreshold"`
+			ExplainSlowQueries bool `yaml:"explainSlowQueries"`
 		}
 	}
 
@@ -119,10 +161,31 @@ func run(log *zap.SugaredLogger) error {
 		return fmt.Errorf("connecting to db: %w", err)
 	}
 
-	defer func() {
-		log.Infow("shutdown", "status", "stopping database support", "host", cfg.DB.Host)
-		db.Close()
-	}()
+	database.SetSlowQuery(database.SlowQuery{
+		Threshold: time.Duration(cfg.DB.SlowQueryThreshold),
+		Explain:   cfg.DB.ExplainSlowQueries,
+	})
+
+	// =========================================================================================================
+	// LIFECYCLE
+
+	// Components are stopped in the reverse order they are appended so the database is only
+	// closed once the servers using it have drained.
+	lc := lifecycle.New(lifecycle.WithDrainDelay(time.Duration(cfg.Web.DrainDelay)))
+
+	lc.Append(lifecycle.Hook{
+		Name: "database",
+		Stop: func(ctx context.Context) error {
+			log.Infow("shutdown", "status", "stopping database support", "host", cfg.DB.Host)
+			return db.Close()
+		},
+	})
+
+	// =========================================================================================================
+	// IDEMPOTENCY
+
+	// Expired idempotency keys are purged in the background while the service runs.
+	lc.Append(purgeHook(lc, log, idempotency.NewCore(levels.Named(log, "idempotency"), db), time.Duration(cfg.Idempotency.PurgeInterval)))
 
 	// =========================================================================================================
 	// Authentication
@@ -146,23 +209,36 @@ func run(log *zap.SugaredLogger) error {
 	// set the build number when identifying metrics in expvar
 	expvar.NewString("build").Set(build)
 
-	log.Infow("startup", "status", "debug router started", "host", cfg.Web.DebugHost)
-
 	// =========================================================================================================
 	// DEBUG MUX
 
+	// Mask the secrets in the config before it is reported by the info endpoint.
+	effective := *cfg
+	effective.DB.Password = "xxxxxx"
+
 	/** The Debug function returns a mux to listen and serve on for all the debug
 	related endpoints. This includes the standard library endpoints.
 	*/
-	debugMux := handlers.DebugMux(build, log, db)
+	debugMux := handlers.DebugMux(handlers.DebugMuxConfig{
+		Build:  build,
+		Log:    log,
+		DB:     db,
+		Auth:   auth,
+		Ready:  lc.Ready,
+		Levels: levels,
+
+		StartTime: start,
+		Config:    effective,
+	})
 
-	// start the service listening for debug requests.
-	// not concerned about shutting this down with load shedding.
-	go func() {
-		if err := http.ListenAndServe(cfg.Web.DebugHost, debugMux); err != nil {
-			log.Errorw("shutdown", "status", "debug router closed", "host", cfg.Web.DebugHost, "ERROR", err)
-		}
-	}()
+	debug := http.Server{
+		Addr:     cfg.Web.DebugHost,
+		Handler:  debugMux,
+		ErrorLog: zap.NewStdLog(log.Desugar()),
+	}
+
+	// The debug server is stopped after the api server so it can report the shutdown.
+	lc.Append(serverHook(lc, log, "debug", &debug, time.Duration(cfg.Web.ShutdownTimeout)))
 
 	// =========================================================================================================
 	// API MUX
@@ -172,13 +248,62 @@ func run(log *zap.SugaredLogger) error {
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
+	var accessLog *mid.AccessLogConfig
+	if cfg.AccessLog.Enabled {
+		accessLog = &mid.AccessLogConfig{
+			SkipPaths:     cfg.AccessLog.SkipPaths,
+			SlowThreshold: time.Duration(cfg.AccessLog.SlowThreshold),
+			Fields:        cfg.AccessLog.Fields,
+		}
+	}
+
+	var cors *mid.CORSPolicy
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		cors = &mid.CORSPolicy{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           time.Duration(cfg.CORS.MaxAge),
+		}
+	}
+
+	var sameSite http.SameSite
+	switch strings.ToLower(cfg.Cookie.SameSite) {
+	case "", "lax":
+		sameSite = http.SameSiteLaxMode
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		if !cfg.Cookie.Secure {
+			return errors.New("cookie: sameSite none requires a secure cookie")
+		}
+		sameSite = http.SameSiteNoneMode
+	default:
+		return fmt.Errorf("cookie: unknown sameSite %q", cfg.Cookie.SameSite)
+	}
+
 	// Construct the mux for the API calls.
-	apiMux := handlers.APIMux(handlers.APIMuxConfig{
-		Shutdown: shutdown,
-		Log:      log,
-		Auth:     auth,
-		DB:       db,
+	apiMux, err := handlers.APIMux(handlers.APIMuxConfig{
+		Shutdown:  shutdown,
+		Log:       log,
+		Levels:    levels,
+		Auth:      auth,
+		DB:        db,
+		AccessLog: accessLog,
+		CORS:      cors,
+		ShutdownPolicy: web.ShutdownPolicy{
+			Threshold: cfg.Web.IntegrityThreshold,
+			Window:    time.Duration(cfg.Web.IntegrityWindow),
+		},
+		IdempotencyTTL:    time.Duration(cfg.Idempotency.TTL),
+		IdempotencySecret: []byte(cfg.Idempotency.Secret),
+		CookieSecure:      cfg.Cookie.Secure,
+		CookieSameSite:    sameSite,
 	})
+	if err != nil {
+		return fmt.Errorf("constructing api mux: %w", err)
+	}
 
 	// Construct a server to service the requests against a mux
 	api := http.Server{
@@ -190,46 +315,105 @@ func run(log *zap.SugaredLogger) error {
 		ErrorLog:     zap.NewStdLog(log.Desugar()),
 	}
 
-	// Make a channel to listen for erros coming from the listener.
-	// Use a buffered channel so the goroutine can exit if we don't collect this error.
-	// When we shutdown the server ListenAndServe can return straight away because we have a buffer channel of 1.
-	/** If it was unbuffered then then sender and reciever of the channel need to be in sync and because we are
-	running the shutdown case in the select with a timeout then that wont be recieving and therefore will block
-	the return of the ListenAndServe when its time to shutdown. Because the channel is buffered and there is some
-	buffer space available then the ListenAndServe can send the error to the serverErrors channel without there
-	needing to be a receiver waiting to receive on the other side therefore allowing the shutdown of
-	listenAndServe to start as soon as we signal the shutdown of the server.
-	*/
-	serverErrors := make(chan error, 1)
-
-	// Start the service listening for api requests.
-	go func() {
-		log.Infow("startup", "status", "api router started", "host", api.Addr)
-		serverErrors <- api.ListenAndServe()
-	}()
+	lc.Append(serverHook(lc, log, "api", &api, time.Duration(cfg.Web.ShutdownTimeout)))
 
 	// =========================================================================================================
 	// Shutdown
 
+	if err := lc.Start(context.Background()); err != nil {
+		return fmt.Errorf("starting service: %w", err)
+	}
+
 	// Blocking main and waiting for shutdown.
 	select {
-	case err := <-serverErrors:
-		return fmt.Errorf("server error: %w", err)
+	case err := <-lc.Errors():
+		return errors.Join(fmt.Errorf("server error: %w", err), lc.Stop(context.Background()))
 	case sig := <-shutdown:
 		log.Infow("shutdown", "status", "shutdown started", "signal", sig)
 		defer log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
 
-		// Give outstanding requests a deadline for completion.
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Web.ShutdownTimeout))
-		defer cancel()
-
-		// Asking listener to shutdown and shed load.
-		// Shutdown is blocking and will take the context of the timeout.
-		if err := api.Shutdown(ctx); err != nil {
-			api.Close() // if the shutdown times out then close server manually.
-			return fmt.Errorf("could not stop server gracefully: %w", err)
+		// Each component is given its own deadline to stop.
+		if err := lc.Stop(context.Background()); err != nil {
+			return fmt.Errorf("could not stop service gracefully: %w", err)
 		}
 	}
 
 	return nil
 }
+
+// purgeHook returns the lifecycle hook purging the expired idempotency keys every interval. Failures
+// are logged and retried on the next interval. Stopping cancels an in-flight purge and waits for it to
+// return so the database is not closed underneath it.
+func purgeHook(lc *lifecycle.Manager, log *zap.SugaredLogger, core idempotency.Core, interval time.Duration) lifecycle.Hook {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	return lifecycle.Hook{
+		Name: "idempotency",
+		Start: func(context.Context) error {
+			if interval <= 0 {
+				close(done)
+				return nil
+			}
+			lc.Go("idempotency", func() error {
+				defer close(done)
+
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-ticker.C:
+						if err := core.Purge(ctx, time.Now()); err != nil && ctx.Err() == nil {
+							log.Errorw("purging idempotency keys", "ERROR", err)
+						}
+					case <-ctx.Done():
+						return nil
+					}
+				}
+			})
+			return nil
+		},
+		Stop: func(stopCtx context.Context) error {
+			cancel()
+
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	}
+}
+
+// serverHook returns the lifecycle hook for serving requests with the server. Errors from ListenAndServe are
+// reported to the lifecycle manager to begin the shutdown. When stopping, outstanding requests are given until
+// the timeout to complete before the server is closed manually.
+func serverHook(lc *lifecycle.Manager, log *zap.SugaredLogger, name string, srv *http.Server, timeout time.Duration) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			log.Infow("startup", "status", name+" router started", "host", srv.Addr)
+			lc.Go(name, func() error {
+				if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+					return err
+				}
+				return nil
+			})
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			log.Infow("shutdown", "status", "stopping "+name+" router", "host", srv.Addr)
+
+			// Asking listener to shutdown and shed load.
+			// Shutdown is blocking and will take the context of the timeout.
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close() // if the shutdown times out then close server manually.
+				return err
+			}
+			return nil
+		},
+		Timeout: timeout,
+	}
+}
@@ -48,13 +48,18 @@ func TestUsers(t *testing.T) {
 	tl.Describe("User Handlers")
 
 	shutdown := make(chan os.Signal, 1)
+	app, err := handlers.APIMux(handlers.APIMuxConfig{
+		Shutdown: shutdown,
+		Log:      test.Log,
+		Auth:     test.Auth,
+		DB:       test.DB,
+	})
+	if err != nil {
+		t.Fatalf("constructing api mux: %v", err)
+	}
+
 	tests := UserTests{
-		app: handlers.APIMux(handlers.APIMuxConfig{
-			Shutdown: shutdown,
-			Log:      test.Log,
-			Auth:     test.Auth,
-			DB:       test.DB,
-		}),
+		app:       app,
 		userToken: test.Token("user@example.com", "gophers"),
 		tl:        tl,
 	}
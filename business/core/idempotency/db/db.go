@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rdforte/go-service/business/sys/database"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for idempotency record access.
+type Store struct {
+	log    *zap.SugaredLogger
+	sqlxDB *sqlx.DB
+}
+
+// NewStore constructs a data for api access.
+func NewStore(log *zap.SugaredLogger, sqlxDB *sqlx.DB) Store {
+	return Store{
+		log:    log,
+		sqlxDB: sqlxDB,
+	}
+}
+
+// Create inserts a new record into the database. It reports false when a record for
+// the key already exists.
+func (s Store) Create(ctx context.Context, rec Record) (bool, error) {
+	const q = `
+	INSERT INTO idempotency_keys
+		(idempotency_key, subject, fingerprint, status_code, header, body, completed, date_created, date_expires)
+	VALUES
+		(:idempotency_key, :subject, :fingerprint, :status_code, :header, :body, :completed, :date_created, :date_expires)
+	ON CONFLICT DO NOTHING`
+
+	rows, err := database.NamedExecContextAffected(ctx, s.log, s.sqlxDB, q, rec)
+	if err != nil {
+		return false, fmt.Errorf("inserting key[%s]: %w", rec.Key, err)
+	}
+
+	return rows == 1, nil
+}
+
+// Takeover replaces a record which has expired, or was never completed and was created
+// before the abandoned time, with a new record for the key. It reports false when the
+// existing record is still in use.
+func (s Store) Takeover(ctx context.Context, rec Record, abandoned time.Time) (bool, error) {
+	data := struct {
+		Record
+		Abandoned time.Time `db:"abandoned"`
+	}{
+		Record:    rec,
+		Abandoned: abandoned,
+	}
+
+	const q = `
+	UPDATE
+		idempotency_keys
+	SET
+		"fingerprint" = :fingerprint,
+		"status_code" = :status_code,
+		"header" = :header,
+		"body" = :body,
+		"completed" = :completed,
+		"date_created" = :date_created,
+		"date_expires" = :date_expires
+	WHERE
+		idempotency_key = :idempotency_key AND
+		subject = :subject AND
+		(date_expires <= :date_created OR (completed = false AND date_created <= :abandoned))`
+
+	rows, err := database.NamedExecContextAffected(ctx, s.log, s.sqlxDB, q, data)
+	if err != nil {
+		return false, fmt.Errorf("taking over key[%s]: %w", rec.Key, err)
+	}
+
+	return rows == 1, nil
+}
+
+// Complete stores the response of the request the record was created for.
+func (s Store) Complete(ctx context.Context, rec Record) error {
+	const q = `
+	UPDATE
+		idempotency_keys
+	SET
+		"status_code" = :status_code,
+		"header" = :header,
+		"body" = :body,
+		"completed" = true
+	WHERE
+		idempotency_key = :idempotency_key AND
+		subject = :subject`
+
+	if err := database.NamedExecContext(ctx, s.log, s.sqlxDB, q, rec); err != nil {
+		return fmt.Errorf("completing key[%s]: %w", rec.Key, err)
+	}
+
+	return nil
+}
+
+// Delete removes a record from the database.
+func (s Store) Delete(ctx context.Context, key string, subject string) error {
+	data := struct {
+		Key     string `db:"idempotency_key"`
+		Subject string `db:"subject"`
+	}{
+		Key:     key,
+		Subject: subject,
+	}
+
+	const q = `
+	DELETE FROM
+		idempotency_keys
+	WHERE
+		idempotency_key = :idempotency_key AND
+		subject = :subject`
+
+	if err := database.NamedExecContext(ctx, s.log, s.sqlxDB, q, data); err != nil {
+		return fmt.Errorf("deleting key[%s]: %w", key, err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes the records which expired before now.
+func (s Store) DeleteExpired(ctx context.Context, now time.Time) error {
+	data := struct {
+		Now time.Time `db:"now"`
+	}{
+		Now: now,
+	}
+
+	const q = `
+	DELETE FROM
+		idempotency_keys
+	WHERE
+		date_expires <= :now`
+
+	if err := database.NamedExecContext(ctx, s.log, s.sqlxDB, q, data); err != nil {
+		return fmt.Errorf("deleting expired keys: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByKey gets the record for the key from the database.
+func (s Store) QueryByKey(ctx context.Context, key string, subject string) (Record, error) {
+	data := struct {
+		Key     string `db:"idempotency_key"`
+		Subject string `db:"subject"`
+	}{
+		Key:     key,
+		Subject: subject,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		idempotency_keys
+	WHERE
+		idempotency_key = :idempotency_key AND
+		subject = :subject`
+
+	var rec Record
+	if err := database.NamedQueryStruct(ctx, s.log, s.sqlxDB, q, data, &rec); err != nil {
+		return Record{}, fmt.Errorf("selecting key[%q]: %w", key, err)
+	}
+
+	return rec, nil
+}
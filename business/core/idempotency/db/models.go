@@ -0,0 +1,17 @@
+package db
+
+import "time"
+
+// Record represent the structure we need for moving data
+// between the app and the database.
+type Record struct {
+	Key         string    `db:"idempotency_key"`
+	Subject     string    `db:"subject"`
+	Fingerprint string    `db:"fingerprint"`
+	StatusCode  int       `db:"status_code"`
+	Header      string    `db:"header" log:"redact"`
+	Body        []byte    `db:"body" log:"redact"`
+	Completed   bool      `db:"completed"`
+	DateCreated time.Time `db:"date_created"`
+	DateExpires time.Time `db:"date_expires"`
+}
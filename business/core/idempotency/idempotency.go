@@ -0,0 +1,193 @@
+// Package idempotency provides the core business API for requests made with an idempotency key.
+// The response of the first request made with a key is stored so retries of the request are
+// replayed the same response instead of being executed again.
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rdforte/go-service/business/core/idempotency/db"
+	"github.com/rdforte/go-service/business/sys/database"
+	"github.com/rdforte/go-service/business/sys/validate"
+	"go.uber.org/zap"
+)
+
+// Set of stable error codes for idempotent requests.
+const (
+	CodeKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	CodeInFlight  = "IDEMPOTENCY_IN_FLIGHT"
+)
+
+// Set of error variables for idempotent requests.
+var (
+	ErrKeyReused = validate.NewError(CodeKeyReused, "idempotency key was used for a different request")
+	ErrInFlight  = validate.NewError(CodeInFlight, "a request with the idempotency key is in progress")
+)
+
+// abandonAfter is how long a request may hold a key before it is considered to have failed
+// without releasing it, ie: the service stopped mid request.
+const abandonAfter = time.Minute
+
+// init registers the HTTP status and the translated messages for the idempotency error codes.
+func init() {
+	validate.RegisterStatus(CodeKeyReused, http.StatusUnprocessableEntity)
+	validate.RegisterStatus(CodeInFlight, http.StatusConflict)
+
+	messages := map[string]map[string]string{
+		CodeKeyReused: {
+			"fr": "la clé d'idempotence a été utilisée pour une autre requête",
+			"de": "der Idempotenzschlüssel wurde für eine andere Anfrage verwendet",
+			"es": "la clave de idempotencia se usó para otra solicitud",
+		},
+		CodeInFlight: {
+			"fr": "une requête avec la clé d'idempotence est en cours",
+			"de": "eine Anfrage mit dem Idempotenzschlüssel wird bereits bearbeitet",
+			"es": "una solicitud con la clave de idempotencia está en curso",
+		},
+	}
+
+	for code, msgs := range messages {
+		if err := validate.RegisterMessages(code, msgs); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Core manages the set of API's for idempotency key access.
+type Core struct {
+	store db.Store
+}
+
+// NewCore constructs a core for idempotency key access.
+func NewCore(log *zap.SugaredLogger, sqlxDB *sqlx.DB) Core {
+	return Core{
+		store: db.NewStore(log, sqlxDB),
+	}
+}
+
+// Begin claims the key for the request until the ttl passes. When the key was already used by
+// the same request the stored record is returned so its response can be replayed, which is
+// reported by the returned bool. ErrKeyReused is returned when the key was used by a different
+// request and ErrInFlight while the request which claimed the key has not completed.
+func (c Core) Begin(ctx context.Context, nr NewRecord, ttl time.Duration, now time.Time) (Record, bool, error) {
+	dbRec := db.Record{
+		Key:         nr.Key,
+		Subject:     nr.Subject,
+		Fingerprint: nr.Fingerprint,
+		Body:        []byte{},
+		DateCreated: now,
+		DateExpires: now.Add(ttl),
+	}
+
+	created, err := c.store.Create(ctx, dbRec)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("begin: %w", err)
+	}
+	if created {
+		rec, err := toRecord(dbRec)
+		return rec, false, err
+	}
+
+	// The key is free again once its record expired or the request holding it was abandoned.
+	taken, err := c.store.Takeover(ctx, dbRec, now.Add(-abandonAfter))
+	if err != nil {
+		return Record{}, false, fmt.Errorf("begin: %w", err)
+	}
+	if taken {
+		rec, err := toRecord(dbRec)
+		return rec, false, err
+	}
+
+	existing, err := c.store.QueryByKey(ctx, nr.Key, nr.Subject)
+	if err != nil {
+
+		// The request holding the key released it in the meantime.
+		if errors.Is(err, database.ErrDBNotFound) {
+			return Record{}, false, ErrInFlight
+		}
+		return Record{}, false, fmt.Errorf("begin: %w", err)
+	}
+
+	switch {
+	case existing.Fingerprint != nr.Fingerprint:
+		return Record{}, false, ErrKeyReused
+	case !existing.Completed:
+		return Record{}, false, ErrInFlight
+	}
+
+	rec, err := toRecord(existing)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("begin: %w", err)
+	}
+
+	return rec, true, nil
+}
+
+// Complete stores the response of the request holding the key so it is replayed on retries.
+func (c Core) Complete(ctx context.Context, key string, subject string, resp Response) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return fmt.Errorf("encoding header: %w", err)
+	}
+
+	dbRec := db.Record{
+		Key:        key,
+		Subject:    subject,
+		StatusCode: resp.StatusCode,
+		Header:     string(header),
+		Body:       resp.Body,
+	}
+
+	if err := c.store.Complete(ctx, dbRec); err != nil {
+		return fmt.Errorf("complete: %w", err)
+	}
+
+	return nil
+}
+
+// Release frees the key when the request failed so it can be retried.
+func (c Core) Release(ctx context.Context, key string, subject string) error {
+	if err := c.store.Delete(ctx, key, subject); err != nil {
+		return fmt.Errorf("release: %w", err)
+	}
+
+	return nil
+}
+
+// Purge removes the records which expired before now.
+func (c Core) Purge(ctx context.Context, now time.Time) error {
+	if err := c.store.DeleteExpired(ctx, now); err != nil {
+		return fmt.Errorf("purge: %w", err)
+	}
+
+	return nil
+}
+
+// toRecord converts a db.Record to idempotency.Record
+func toRecord(dbRec db.Record) (Record, error) {
+	var header map[string][]string
+	if dbRec.Header != "" {
+		if err := json.Unmarshal([]byte(dbRec.Header), &header); err != nil {
+			return Record{}, fmt.Errorf("decoding header: %w", err)
+		}
+	}
+
+	rec := Record{
+		Key:         dbRec.Key,
+		Subject:     dbRec.Subject,
+		Fingerprint: dbRec.Fingerprint,
+		StatusCode:  dbRec.StatusCode,
+		Header:      header,
+		Body:        dbRec.Body,
+		Completed:   dbRec.Completed,
+		DateCreated: dbRec.DateCreated,
+		DateExpires: dbRec.DateExpires,
+	}
+	return rec, nil
+}
@@ -0,0 +1,31 @@
+package idempotency
+
+import "time"
+
+// Record represents the response of a request made with an idempotency key.
+type Record struct {
+	Key         string
+	Subject     string
+	Fingerprint string
+	StatusCode  int
+	Header      map[string][]string
+	Body        []byte
+	Completed   bool
+	DateCreated time.Time
+	DateExpires time.Time
+}
+
+// NewRecord contains the information needed to claim an idempotency key for a request.
+type NewRecord struct {
+	Key         string
+	Subject     string
+	Fingerprint string
+}
+
+// Response contains the response of the request which is replayed on retries. Header holds
+// the response headers the replay needs.
+type Response struct {
+	StatusCode int
+	Header     map[string][]string
+	Body       []byte
+}
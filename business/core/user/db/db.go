@@ -6,6 +6,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/rdforte/go-service/business/sys/database"
+	"github.com/rdforte/go-service/business/sys/paging"
 	"go.uber.org/zap"
 )
 
@@ -27,9 +28,9 @@ func NewStore(log *zap.SugaredLogger, sqlxDB *sqlx.DB) Store {
 func (s Store) Create(ctx context.Context, usr User) error {
 	const q = `
 	INSERT INTO users
-		(user_id, name, email, password_hash, roles, date_created, date_updated)
+		(user_id, name, email, password_hash, roles, date_created, date_updated, version)
 	VALUES
-		(:user_id, :name, :email, :password_hash, :roles, :date_created, :date_updated)`
+		(:user_id, :name, :email, :password_hash, :roles, :date_created, :date_updated, :version)`
 
 	if err := database.NamedExecContext(ctx, s.log, s.sqlxDB, q, usr); err != nil {
 		return fmt.Errorf("inserting user: %w", err)
@@ -38,7 +39,9 @@ func (s Store) Create(ctx context.Context, usr User) error {
 	return nil
 }
 
-// Update replaces a user document in the database.
+// Update replaces a user document in the database and increments its version. The user
+// is only updated if it is still at the version it was read at, otherwise ErrDBConflict
+// is returned.
 func (s Store) Update(ctx context.Context, usr User) error {
 	const q = `
 	UPDATE
@@ -48,13 +51,19 @@ func (s Store) Update(ctx context.Context, usr User) error {
 		"email" = :email,
 		"roles" = :roles,
 		"password_hash" = :password_hash,
-		"date_updated" = :date_updated
+		"date_updated" = :date_updated,
+		"version" = version + 1
 	WHERE
-		user_id = :user_id`
+		user_id = :user_id AND
+		version = :version`
 
-	if err := database.NamedExecContext(ctx, s.log, s.sqlxDB, q, usr); err != nil {
+	rows, err := database.NamedExecContextAffected(ctx, s.log, s.sqlxDB, q, usr)
+	if err != nil {
 		return fmt.Errorf("updating userID[%s]: %w", usr.ID, err)
 	}
+	if rows == 0 {
+		return fmt.Errorf("updating userID[%s] version[%d]: %w", usr.ID, usr.Version, database.ErrDBConflict)
+	}
 
 	return nil
 }
@@ -80,33 +89,116 @@ func (s Store) Delete(ctx context.Context, userID string) error {
 	return nil
 }
 
-// Query retrieves a list of existing users from the database.
-func (s Store) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]User, error) {
+// DeleteVersion removes a user from the database if it is still at the version. Otherwise
+// ErrDBConflict is returned.
+func (s Store) DeleteVersion(ctx context.Context, userID string, version int) error {
 	data := struct {
-		Offset      int `db:"offset"`
-		RowsPerPage int `db:"rows_per_page"`
+		UserID  string `db:"user_id"`
+		Version int    `db:"version"`
 	}{
-		Offset:      (pageNumber - 1) * rowsPerPage,
-		RowsPerPage: rowsPerPage,
+		UserID:  userID,
+		Version: version,
 	}
 
+	const q = `
+	DELETE FROM
+		users
+	WHERE
+		user_id = :user_id AND
+		version = :version`
+
+	rows, err := database.NamedExecContextAffected(ctx, s.log, s.sqlxDB, q, data)
+	if err != nil {
+		return fmt.Errorf("deleting userID[%s]: %w", userID, err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("deleting userID[%s] version[%d]: %w", userID, version, database.ErrDBConflict)
+	}
+
+	return nil
+}
+
+// Query retrieves a page of users matching the filter from the database using the keyset of
+// the cursor. One more row than the limit is fetched so the caller knows whether more follow.
+func (s Store) Query(ctx context.Context, filter Filter, req paging.Request) ([]User, error) {
 	const q = `
 	SELECT
 		*
 	FROM
-		users
-	ORDER BY
-		user_id
-	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+		users`
+
+	b := applyFilter(database.NewBuilder(q), filter)
+
+	// The rows after the cursor in the order they are fetched, ties are broken by the id.
+	direction := paging.ASC
+	if req.Descending() {
+		direction = paging.DESC
+	}
+	if req.Cursor != nil {
+		if column := orderByColumns[req.OrderBy.Field]; column == "user_id" {
+			b.After([]string{"user_id"}, []interface{}{req.Cursor.ID}, req.Descending())
+		} else {
+			b.After([]string{column, "user_id"}, []interface{}{req.Cursor.Value, req.Cursor.ID}, req.Descending())
+		}
+	}
+
+	query, data, err := b.
+		OrderBy(req.OrderBy.Field, direction, orderByColumns, "user_id").
+		Limit(req.Limit + 1).
+		Query()
+	if err != nil {
+		return nil, fmt.Errorf("building query: %w", err)
+	}
 
 	var usrs []User
-	if err := database.NamedQuerySlice(ctx, s.log, s.sqlxDB, q, data, &usrs); err != nil {
+	if err := database.NamedQuerySlice(ctx, s.log, s.sqlxDB, query, data, &usrs); err != nil {
 		return nil, fmt.Errorf("selecting users: %w", err)
 	}
 
 	return usrs, nil
 }
 
+// Count returns the number of users matching the filter.
+func (s Store) Count(ctx context.Context, filter Filter) (int, error) {
+	const q = `
+	SELECT
+		*
+	FROM
+		users`
+
+	query, data, err := applyFilter(database.NewBuilder(q), filter).CountQuery()
+	if err != nil {
+		return 0, fmt.Errorf("building query: %w", err)
+	}
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.sqlxDB, query, data, &count); err != nil {
+		return 0, fmt.Errorf("counting users: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// orderByColumns whitelists the columns users can be ordered by.
+var orderByColumns = map[string]string{
+	"id":           "user_id",
+	"name":         "name",
+	"email":        "email",
+	"date_created": "date_created",
+}
+
+// applyFilter adds the conditions of the filter to the query.
+func applyFilter(b *database.Builder, filter Filter) *database.Builder {
+	return b.
+		Contains("name", filter.Name).
+		Where("email", database.OpEqual, filter.Email).
+		Has("roles", filter.Role).
+		Where("date_created", database.OpGreaterOrEqual, filter.StartCreatedDate).
+		Where("date_created", database.OpLess, filter.EndCreatedDate)
+}
+
 // QueryByID gets the specified user from the database.
 func (s Store) QueryByID(ctx context.Context, userID string) (User, error) {
 	data := struct {
@@ -11,9 +11,19 @@ import (
 type User struct {
 	ID           string         `db:"user_id"`
 	Name         string         `db:"name"`
-	Email        string         `db:"email"`
+	Email        string         `db:"email" log:"redact"`
 	Roles        pq.StringArray `db:"roles"`
-	PasswordHash []byte         `db:"password_hash"`
+	PasswordHash []byte         `db:"password_hash" log:"redact"`
 	DateCreated  time.Time      `db:"date_created"`
 	DateUpdated  time.Time      `db:"date_updated"`
+	Version      int            `db:"version"`
+}
+
+// Filter holds the values users are filtered by. Nil fields are not filtered on.
+type Filter struct {
+	Name             *string
+	Email            *string
+	Role             *string
+	StartCreatedDate *time.Time
+	EndCreatedDate   *time.Time
 }
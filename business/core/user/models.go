@@ -1,6 +1,11 @@
 package user
 
-import "time"
+import (
+	"time"
+
+	"github.com/rdforte/go-service/business/sys/paging"
+	"github.com/rdforte/go-service/business/sys/validate"
+)
 
 // User represents an individual user
 type User struct {
@@ -11,17 +16,47 @@ type User struct {
 	PasswordHash []byte    `json:"-"`
 	DateCreated  time.Time `json:"date_created"`
 	DateUpdated  time.Time `json:"date_updated"`
+	Version      int       `json:"version"`
+}
+
+// Set of fields users can be ordered by.
+const (
+	OrderByID          = "id"
+	OrderByName        = "name"
+	OrderByEmail       = "email"
+	OrderByDateCreated = "date_created"
+)
+
+// OrderByFields are the fields users can be ordered by.
+var OrderByFields = []string{OrderByID, OrderByName, OrderByEmail, OrderByDateCreated}
+
+// DefaultOrderBy orders users by their id.
+var DefaultOrderBy = paging.OrderBy{Field: OrderByID, Direction: paging.ASC}
+
+// QueryFilter holds the available fields a query can be filtered on. Nil fields
+// are not filtered on. Name matches any user whose name contains it.
+type QueryFilter struct {
+	Name             *string    `validate:"omitempty,min=1"`
+	Email            *string    `validate:"omitempty,email"`
+	Role             *string    `validate:"omitempty,role"`
+	StartCreatedDate *time.Time `validate:"omitempty"`
+	EndCreatedDate   *time.Time `validate:"omitempty"`
 }
 
 // NewUser contains information needed to create a new User.
 type NewUser struct {
 	Name            string   `json:"name" validate:"required"`
 	Email           string   `json:"email" validate:"required,email"`
-	Roles           []string `json:"roles" validate:"required"`
-	Password        string   `json:"password" validate:"required"`
+	Roles           []string `json:"roles" validate:"required,dive,role"`
+	Password        string   `json:"password" validate:"required,password"`
 	PasswordConfirm string   `json:"password_confirm" validate:"eqfield=Password"`
 }
 
+// Validate checks the data in the model is considered clean.
+func (nu NewUser) Validate() error {
+	return validate.Check(nu)
+}
+
 // UpdateUser defines what information may be provided to modify an existing
 // User. All fields are optional so clients can send just the fields they want
 // changed. It uses pointer fields so we can differentiate between a field that
@@ -33,7 +68,12 @@ type NewUser struct {
 type UpdateUser struct {
 	Name            *string  `json:"name"`
 	Email           *string  `json:"email" validate:"omitempty,email"`
-	Roles           []string `json:"roles"`
-	Password        *string  `json:"password"`
+	Roles           []string `json:"roles" validate:"omitempty,dive,role"`
+	Password        *string  `json:"password" validate:"omitempty,password"`
 	PasswordConfirm *string  `json:"password_confirm" validate:"omitempty,eqfield=Password"`
 }
+
+// Validate checks the data in the model is considered clean.
+func (uu UpdateUser) Validate() error {
+	return validate.Check(uu)
+}
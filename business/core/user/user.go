@@ -7,26 +7,83 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"time"
 	"unsafe"
 
-	"github.com/ardanlabs/service/business/sys/validate"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/jmoiron/sqlx"
 	"github.com/rdforte/go-service/business/core/user/db"
 	"github.com/rdforte/go-service/business/sys/auth"
 	"github.com/rdforte/go-service/business/sys/database"
+	"github.com/rdforte/go-service/business/sys/paging"
+	"github.com/rdforte/go-service/business/sys/validate"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Set of stable error codes for CRUD operations.
+const (
+	CodeNotFound              = "USER_NOT_FOUND"
+	CodeInvalidID             = "USER_INVALID_ID"
+	CodeAuthenticationFailure = "USER_AUTHENTICATION_FAILED"
+	CodePreconditionFailed    = "USER_PRECONDITION_FAILED"
+	CodeConflict              = "USER_CONFLICT"
+)
+
 // Set of error variables for CRUD operations.
 var (
-	ErrNotFound              = errors.New("user not found")
-	ErrInvalidID             = errors.New("ID is not in its proper format")
-	ErrAuthenticationFailure = errors.New("authentication failed")
+	ErrNotFound              = validate.NewError(CodeNotFound, "user not found")
+	ErrInvalidID             = validate.NewError(CodeInvalidID, "ID is not in its proper format")
+	ErrAuthenticationFailure = validate.NewError(CodeAuthenticationFailure, "authentication failed")
+	ErrPreconditionFailed    = validate.NewError(CodePreconditionFailed, "user has been modified")
+	ErrConflict              = validate.NewError(CodeConflict, "user was modified concurrently")
 )
 
+// init registers the HTTP status and the translated messages for the user error codes.
+func init() {
+	validate.RegisterStatus(CodeNotFound, http.StatusNotFound)
+	validate.RegisterStatus(CodeInvalidID, http.StatusBadRequest)
+	validate.RegisterStatus(CodeAuthenticationFailure, http.StatusUnauthorized)
+	validate.RegisterStatus(CodePreconditionFailed, http.StatusPreconditionFailed)
+	validate.RegisterStatus(CodeConflict, http.StatusConflict)
+
+	messages := map[string]map[string]string{
+		CodeNotFound: {
+			"fr": "utilisateur introuvable",
+			"de": "Benutzer nicht gefunden",
+			"es": "usuario no encontrado",
+		},
+		CodeInvalidID: {
+			"fr": "l'identifiant n'est pas dans un format valide",
+			"de": "die ID hat kein gültiges Format",
+			"es": "el ID no tiene el formato correcto",
+		},
+		CodeAuthenticationFailure: {
+			"fr": "échec de l'authentification",
+			"de": "Authentifizierung fehlgeschlagen",
+			"es": "error de autenticación",
+		},
+		CodePreconditionFailed: {
+			"fr": "l'utilisateur a été modifié",
+			"de": "der Benutzer wurde geändert",
+			"es": "el usuario ha sido modificado",
+		},
+		CodeConflict: {
+			"fr": "l'utilisateur a été modifié simultanément",
+			"de": "der Benutzer wurde gleichzeitig geändert",
+			"es": "el usuario fue modificado simultáneamente",
+		},
+	}
+
+	for code, msgs := range messages {
+		if err := validate.RegisterMessages(code, msgs); err != nil {
+			panic(err)
+		}
+	}
+}
+
 // Core manages the set of API's for user access.
 type Core struct {
 	store db.Store
@@ -45,6 +102,40 @@ func toUser(dbUsr db.User) User {
 	return *u
 }
 
+// matchVersion reports whether the user is at one of the versions, no versions match any.
+func matchVersion(usr User, versions []string) bool {
+	if len(versions) == 0 {
+		return true
+	}
+
+	for _, version := range versions {
+		if version == strconv.Itoa(usr.Version) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCursor validates the key of the cursor against the types of the columns, since the
+// client may have tampered with it and the query would fail.
+func checkCursor(c *paging.Cursor) error {
+	if c == nil {
+		return nil
+	}
+
+	if err := validate.CheckID(c.ID); err != nil {
+		return paging.ErrInvalidCursor
+	}
+
+	if c.Field == OrderByDateCreated {
+		if _, err := time.Parse(time.RFC3339Nano, c.Value); err != nil {
+			return paging.ErrInvalidCursor
+		}
+	}
+
+	return nil
+}
+
 // toUserSlice converts a slice of db.User to a slice of user.User
 func toUserSlice(dbUsrs []db.User) []User {
 	users := make([]User, len(dbUsrs))
@@ -73,6 +164,7 @@ func (c Core) Create(ctx context.Context, nu NewUser, now time.Time) (User, erro
 		Roles:        nu.Roles,
 		DateCreated:  now,
 		DateUpdated:  now,
+		Version:      1,
 	}
 
 	if err := c.store.Create(ctx, dbUsr); err != nil {
@@ -82,8 +174,10 @@ func (c Core) Create(ctx context.Context, nu NewUser, now time.Time) (User, erro
 	return toUser(dbUsr), nil
 }
 
-// Update replaces a user document in the database.
-func (c Core) Update(ctx context.Context, userID string, uu UpdateUser, now time.Time) error {
+// Update replaces a user document in the database. When versions are provided the user is
+// only updated if it is still at one of them, otherwise ErrPreconditionFailed is returned.
+// ErrConflict is returned when the user was changed by another request while updating.
+func (c Core) Update(ctx context.Context, userID string, uu UpdateUser, now time.Time, versions ...string) error {
 	if err := validate.CheckID(userID); err != nil {
 		return ErrInvalidID
 	}
@@ -100,6 +194,10 @@ func (c Core) Update(ctx context.Context, userID string, uu UpdateUser, now time
 		return fmt.Errorf("updating user userID[%s]: %w", userID, err)
 	}
 
+	if !matchVersion(toUser(dbUsr), versions) {
+		return ErrPreconditionFailed
+	}
+
 	if uu.Name != nil {
 		dbUsr.Name = *uu.Name
 	}
@@ -119,33 +217,106 @@ func (c Core) Update(ctx context.Context, userID string, uu UpdateUser, now time
 	dbUsr.DateUpdated = now
 
 	if err := c.store.Update(ctx, dbUsr); err != nil {
+		if errors.Is(err, database.ErrDBConflict) {
+			return ErrConflict
+		}
 		return fmt.Errorf("udpate: %w", err)
 	}
 
 	return nil
 }
 
-// Delete removes a user from the database.
-func (c Core) Delete(ctx context.Context, userID string) error {
+// Delete removes a user from the database. When versions are provided the user is only
+// removed if it is still at one of them, otherwise ErrPreconditionFailed is returned.
+func (c Core) Delete(ctx context.Context, userID string, versions ...string) error {
 	if err := validate.CheckID(userID); err != nil {
 		return ErrInvalidID
 	}
 
-	if err := c.store.Delete(ctx, userID); err != nil {
+	if len(versions) == 0 {
+		if err := c.store.Delete(ctx, userID); err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+		return nil
+	}
+
+	// The user is deleted in the same statement the version is checked in, so it can't be
+	// updated in between. A user is only ever at one version so at most one delete succeeds.
+	for _, version := range versions {
+		v, err := strconv.Atoi(version)
+		if err != nil {
+			continue
+		}
+
+		err = c.store.DeleteVersion(ctx, userID, v)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, database.ErrDBConflict) {
+			return fmt.Errorf("delete: %w", err)
+		}
+	}
+
+	// Nothing was deleted, tell a missing user apart from one at another version.
+	if _, err := c.store.QueryByID(ctx, userID); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return ErrNotFound
+		}
 		return fmt.Errorf("delete: %w", err)
 	}
 
-	return nil
+	return ErrPreconditionFailed
 }
 
-// Query retrieves a list of existing users from the database.
-func (c Core) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]User, error) {
-	dbUsers, err := c.store.Query(ctx, pageNumber, rowsPerPage)
+// Query retrieves the page of users matching the filter from the database.
+func (c Core) Query(ctx context.Context, filter QueryFilter, req paging.Request) (paging.Page[User], error) {
+	if err := validate.Check(filter); err != nil {
+		return paging.Page[User]{}, fmt.Errorf("validating filter: %w", err)
+	}
+
+	dbFilter := db.Filter{
+		Name:             filter.Name,
+		Email:            filter.Email,
+		Role:             filter.Role,
+		StartCreatedDate: filter.StartCreatedDate,
+		EndCreatedDate:   filter.EndCreatedDate,
+	}
+
+	// Dates are stored in UTC without a time zone.
+	for _, date := range []**time.Time{&dbFilter.StartCreatedDate, &dbFilter.EndCreatedDate} {
+		if *date != nil {
+			utc := (*date).UTC()
+			*date = &utc
+		}
+	}
+
+	if err := checkCursor(req.Cursor); err != nil {
+		return paging.Page[User]{}, err
+	}
+
+	dbUsers, err := c.store.Query(ctx, dbFilter, req)
+	if err != nil {
+		return paging.Page[User]{}, fmt.Errorf("query: %w", err)
+	}
+
+	total, err := c.store.Count(ctx, dbFilter)
 	if err != nil {
-		return nil, fmt.Errorf("query: %w", err)
+		return paging.Page[User]{}, fmt.Errorf("query: %w", err)
+	}
+
+	key := func(usr User) (string, string) {
+		switch req.OrderBy.Field {
+		case OrderByName:
+			return usr.Name, usr.ID
+		case OrderByEmail:
+			return usr.Email, usr.ID
+		case OrderByDateCreated:
+			return usr.DateCreated.UTC().Format(time.RFC3339Nano), usr.ID
+		}
+		return usr.ID, usr.ID
 	}
 
-	return toUserSlice(dbUsers), nil
+	return paging.NewPage(req, toUserSlice(dbUsers), total, key), nil
 }
 
 // QueryByID gets the specified user from the database.
@@ -4,13 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/rdforte/go-service/business/core/user"
+	"github.com/rdforte/go-service/business/core/user/db"
 	"github.com/rdforte/go-service/business/data/dbtest"
 	"github.com/rdforte/go-service/business/sys/auth"
+	"github.com/rdforte/go-service/business/sys/database"
+	"github.com/rdforte/go-service/business/sys/paging"
 	"github.com/rdforte/go-service/foundation/logger"
 )
 
@@ -23,10 +27,10 @@ var dbc = dbtest.DBContainer{
 func TestUser(t *testing.T) {
 	tl := logger.NewTestLog(t)
 
-	log, db, teardown := dbtest.NewUnit(t, dbc)
+	log, dbConn, teardown := dbtest.NewUnit(t, dbc)
 	t.Cleanup(teardown)
 
-	core := user.NewCore(log, db)
+	core := user.NewCore(log, dbConn)
 
 	tl.Describe("Working with User records")
 	{
@@ -39,8 +43,8 @@ func TestUser(t *testing.T) {
 			Name:            "Master Yoda",
 			Email:           "masteryoda@1lbladfaulbahnaldjbjbadanl23456.com",
 			Roles:           []string{auth.RoleAdmin},
-			Password:        "gophers",
-			PasswordConfirm: "gophers",
+			Password:        "gophers123",
+			PasswordConfirm: "gophers123",
 		}
 
 		// Create User.
@@ -95,16 +99,67 @@ func TestUser(t *testing.T) {
 		}
 		tl.Success("Should have been able to update name")
 
-		// Delete user.
-		if err := core.Delete(ctx, usr.ID); err != nil {
+		// Check the version was incremented.
+		if saved.Version != usr.Version+1 {
+			tl.Failed("Should have incremented the version",
+				fmt.Errorf("Versions do not match: [%d : %d]", saved.Version, usr.Version+1))
+		}
+		tl.Success("Should have incremented the version")
+
+		// Update user at a stale version.
+		if err := core.Update(ctx, usr.ID, upd, now, strconv.Itoa(usr.Version)); !errors.Is(err, user.ErrPreconditionFailed) {
+			tl.Failed("Should not be able to update a stale version", err)
+		}
+		tl.Success("Should not be able to update a stale version")
+
+		// Write a user read before the last update, as a request racing it would.
+		store := db.NewStore(log, dbConn)
+		stale, err := store.QueryByID(ctx, usr.ID)
+		if err != nil {
+			tl.Failed("Should be able to retrieve user by ID from the store", err)
+		}
+		tl.Success("Should be able to retrieve user by ID from the store")
+
+		stale.Version = usr.Version
+		if err := store.Update(ctx, stale); !errors.Is(err, database.ErrDBConflict) {
+			tl.Failed("Should not be able to write a stale version", err)
+		}
+		tl.Success("Should not be able to write a stale version")
+
+		// Delete user at a stale version.
+		if err := core.Delete(ctx, usr.ID, strconv.Itoa(usr.Version)); !errors.Is(err, user.ErrPreconditionFailed) {
+			tl.Failed("Should not be able to delete a stale version", err)
+		}
+		tl.Success("Should not be able to delete a stale version")
+
+		// Delete user at the current version.
+		if err := core.Delete(ctx, usr.ID, strconv.Itoa(usr.Version), strconv.Itoa(saved.Version)); err != nil {
 			tl.Failed("Should be able to delete user", err)
 		}
 		tl.Success("Should be able to delete user")
 
+		// Delete user again.
+		if err := core.Delete(ctx, usr.ID, strconv.Itoa(saved.Version)); !errors.Is(err, user.ErrNotFound) {
+			tl.Failed("Should not be able to delete a deleted user", err)
+		}
+		tl.Success("Should not be able to delete a deleted user")
+
 		// Retrieve Deleted user expecting user to not be in db.
 		if _, err := core.QueryByID(ctx, usr.ID); !errors.Is(err, user.ErrNotFound) {
 			tl.Failed("Should not be able to retrieve user", err)
 		}
 		tl.Success("Should not be ablt to retrieve user")
+
+		// Query users with cursors tampered with by the client.
+		for _, c := range []paging.Cursor{
+			{Field: user.OrderByDateCreated, Value: "yesterday", ID: usr.ID},
+			{Field: user.OrderByID, Value: "1", ID: "1"},
+		} {
+			req := paging.Request{OrderBy: paging.OrderBy{Field: c.Field, Direction: paging.ASC}, Limit: 10, Cursor: &c}
+			if _, err := core.Query(ctx, user.QueryFilter{}, req); !errors.Is(err, paging.ErrInvalidCursor) {
+				tl.Failed("Should not be able to query with an invalid cursor", err)
+			}
+		}
+		tl.Success("Should not be able to query with an invalid cursor")
 	}
 }
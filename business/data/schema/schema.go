@@ -4,6 +4,7 @@ package schema
 import (
 	"context"
 	_ "embed" // calls init function
+	"errors"
 	"fmt"
 
 	"github.com/ardanlabs/darwin"
@@ -39,6 +40,37 @@ func Migrate(ctx context.Context, db *sqlx.DB) error {
 	return d.Migrate()
 }
 
+// StatusCheck returns nil if every migration defined in this package has been applied
+// to db. It returns a non nil error otherwise.
+func StatusCheck(ctx context.Context, db *sqlx.DB) error {
+	driver, err := darwin.NewGenericDriver(db.DB, darwin.PostgresDialect{})
+	if err != nil {
+		return fmt.Errorf("construct darwin driver: %w", err)
+	}
+
+	// Darwin expects at least one applied migration when reporting the status.
+	records, err := driver.All()
+	if err != nil {
+		return fmt.Errorf("reading applied migrations: %w", err)
+	}
+	if len(records) == 0 {
+		return errors.New("no migrations applied")
+	}
+
+	infos, err := darwin.New(driver, darwin.ParseMigrations(schemaDoc)).Info()
+	if err != nil {
+		return fmt.Errorf("reading migrations: %w", err)
+	}
+
+	for _, info := range infos {
+		if info.Status != darwin.Applied {
+			return fmt.Errorf("migration %v %s", info.Migration.Version, info.Status)
+		}
+	}
+
+	return nil
+}
+
 // Seed runs the set of seed-data queries against db. The queries are ran in a
 // transaction and rolled back if any fail.
 func Seed(ctx context.Context, db *sqlx.DB) error {
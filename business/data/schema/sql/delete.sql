@@ -1,3 +1,4 @@
 DELETE FROM sales;
 DELETE FROM products;
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users;
+DELETE FROM idempotency_keys;
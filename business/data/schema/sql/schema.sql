@@ -41,3 +41,23 @@ CREATE TABLE sales (
 	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
 	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE
 );
+
+-- Version: 1.4
+-- Description: Add version to users for optimistic concurrency
+ALTER TABLE users ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- Version: 1.5
+-- Description: Create table idempotency_keys
+CREATE TABLE idempotency_keys (
+	idempotency_key TEXT,
+	subject         TEXT,
+	fingerprint     TEXT,
+	status_code     INT,
+	header          TEXT,
+	body            BYTEA,
+	completed       BOOLEAN,
+	date_created    TIMESTAMP,
+	date_expires    TIMESTAMP,
+
+	PRIMARY KEY (idempotency_key, subject)
+);
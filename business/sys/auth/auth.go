@@ -2,6 +2,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/rsa"
 	"errors"
 	"fmt"
@@ -65,6 +66,15 @@ func New(activeKID string, keyLookup KeyLookup) (*Auth, error) {
 	return &a, nil
 }
 
+// StatusCheck returns nil if the private key for the active KID can still be found in the
+// key store so new tokens can be signed. It returns a non nil error otherwise.
+func (a *Auth) StatusCheck(ctx context.Context) error {
+	if _, err := a.keyLookup.PrivateKey(a.activeKID); err != nil {
+		return fmt.Errorf("active KID[%s] not found in store: %w", a.activeKID, err)
+	}
+	return nil
+}
+
 // GenerateToken generates a signed JWT token string representing the user Claims.
 func (a *Auth) GenerateToken(claims Claims) (string, error) {
 	token := jwt.NewWithClaims(a.method, claims)
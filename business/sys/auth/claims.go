@@ -13,6 +13,15 @@ const (
 	RoleUser  = "USER"
 )
 
+// ValidRole returns true if the role is one of the expected values for Claims.Roles.
+func ValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleUser:
+		return true
+	}
+	return false
+}
+
 // Claims represents the authorization claims transmitted via a JWT.
 type Claims struct {
 	jwt.RegisteredClaims
@@ -0,0 +1,266 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Op is a comparison operator a filter applies to a column.
+type Op string
+
+// Set of comparison operators supported by the Builder.
+const (
+	OpEqual          Op = "="
+	OpNotEqual       Op = "<>"
+	OpLess           Op = "<"
+	OpLessOrEqual    Op = "<="
+	OpGreater        Op = ">"
+	OpGreaterOrEqual Op = ">="
+)
+
+// validOps whitelists the operators so an Op can not be used to inject SQL.
+var validOps = map[Op]bool{
+	OpEqual:          true,
+	OpNotEqual:       true,
+	OpLess:           true,
+	OpLessOrEqual:    true,
+	OpGreater:        true,
+	OpGreaterOrEqual: true,
+}
+
+// identifier matches the column names the Builder accepts.
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// likeEscaper escapes the wildcards of a LIKE pattern so they match literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Builder composes a named query from a SELECT statement, the filters applied in the WHERE
+// clause, a whitelisted ORDER BY and a LIMIT. Every value is bound as a named parameter the
+// Builder names after its column and columns must be plain identifiers, so the query is always safe to
+// execute. Filters with a nil value are not applied so optional filters can be passed as is.
+// The first invalid call is reported by Query and CountQuery.
+type Builder struct {
+	query      string
+	conditions []string
+	orderBy    []string
+	limit      int
+	data       map[string]interface{}
+	err        error
+}
+
+// NewBuilder constructs a Builder for the SELECT statement without a WHERE clause.
+func NewBuilder(query string) *Builder {
+	return &Builder{
+		query: strings.TrimSpace(query),
+		data:  make(map[string]interface{}),
+	}
+}
+
+// Where filters the column by comparing it with the value.
+func (b *Builder) Where(column string, op Op, value interface{}) *Builder {
+	value, ok := deref(value)
+	if !ok || !b.column(column) {
+		return b
+	}
+	if !validOps[op] {
+		b.fail(fmt.Errorf("operator %q is not supported", op))
+		return b
+	}
+
+	b.conditions = append(b.conditions, column+" "+string(op)+" "+b.bind(column, value))
+	return b
+}
+
+// Contains filters the column to the rows containing the value ignoring case.
+func (b *Builder) Contains(column string, value *string) *Builder {
+	if value == nil || !b.column(column) {
+		return b
+	}
+
+	b.conditions = append(b.conditions, column+" ILIKE "+b.bind(column, "%"+likeEscaper.Replace(*value)+"%"))
+	return b
+}
+
+// Has filters the array column to the rows with the value as an element.
+func (b *Builder) Has(column string, value interface{}) *Builder {
+	value, ok := deref(value)
+	if !ok || !b.column(column) {
+		return b
+	}
+
+	b.conditions = append(b.conditions, b.bind(column, value)+" = ANY("+column+")")
+	return b
+}
+
+// After filters the rows to the ones after the key in the order of the columns, which is the
+// keyset of a cursor. The rows before the key are selected when descending.
+func (b *Builder) After(columns []string, key []interface{}, descending bool) *Builder {
+	if len(columns) == 0 || len(columns) != len(key) {
+		b.fail(errors.New("keyset must have a value for every column"))
+		return b
+	}
+	for _, column := range columns {
+		if !b.column(column) {
+			return b
+		}
+	}
+
+	params := make([]string, len(key))
+	for i, value := range key {
+		params[i] = b.bind(columns[i], value)
+	}
+
+	op := OpGreater
+	if descending {
+		op = OpLess
+	}
+
+	b.conditions = append(b.conditions, "("+strings.Join(columns, ", ")+") "+string(op)+" ("+strings.Join(params, ", ")+")")
+	return b
+}
+
+// OrderBy orders the rows by the column the field maps to in the whitelist of columns. The
+// direction is ASC or DESC and the tie breaker columns are ordered in the same direction.
+func (b *Builder) OrderBy(field string, direction string, columns map[string]string, tieBreakers ...string) *Builder {
+	column, ok := columns[field]
+	if !ok {
+		b.fail(fmt.Errorf("order by field %q is not supported", field))
+		return b
+	}
+
+	direction = strings.ToUpper(direction)
+	if direction != "ASC" && direction != "DESC" {
+		b.fail(fmt.Errorf("order by direction %q is not supported", direction))
+		return b
+	}
+
+	for _, c := range append([]string{column}, tieBreakers...) {
+		if !b.column(c) {
+			return b
+		}
+		if !contains(b.orderBy, c+" "+direction) {
+			b.orderBy = append(b.orderBy, c+" "+direction)
+		}
+	}
+	return b
+}
+
+// Limit limits the number of rows selected.
+func (b *Builder) Limit(limit int) *Builder {
+	if limit < 1 {
+		b.fail(fmt.Errorf("limit %d must be positive", limit))
+		return b
+	}
+
+	b.limit = limit
+	return b
+}
+
+// Query returns the named query and its parameters.
+func (b *Builder) Query() (string, map[string]interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+
+	var buf strings.Builder
+	buf.WriteString(b.query)
+	b.writeWhere(&buf)
+
+	if len(b.orderBy) > 0 {
+		buf.WriteString(" ORDER BY " + strings.Join(b.orderBy, ", "))
+	}
+	if b.limit > 0 {
+		buf.WriteString(" LIMIT " + b.bind("limit", b.limit))
+	}
+
+	return buf.String(), b.data, nil
+}
+
+// CountQuery returns the named query counting the rows matching the filters, ignoring the
+// ORDER BY and LIMIT. The count is returned in the count column.
+func (b *Builder) CountQuery() (string, map[string]interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+
+	var buf strings.Builder
+	buf.WriteString("SELECT count(1) AS count FROM (" + b.query)
+	b.writeWhere(&buf)
+	buf.WriteString(") AS filtered")
+
+	return buf.String(), b.data, nil
+}
+
+// writeWhere writes the WHERE clause joining the conditions.
+func (b *Builder) writeWhere(buf *strings.Builder) {
+	if len(b.conditions) > 0 {
+		buf.WriteString(" WHERE " + strings.Join(b.conditions, " AND "))
+	}
+}
+
+// bind adds the value as a parameter named after the column and returns its placeholder. When
+// the column is compared more than once the name is numbered (email_2) which queryString
+// redacts like the column.
+func (b *Builder) bind(column string, value interface{}) string {
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		column = column[i+1:]
+	}
+
+	name := column
+	for n := 2; ; n++ {
+		if _, exists := b.data[name]; !exists {
+			break
+		}
+		name = column + "_" + strconv.Itoa(n)
+	}
+
+	b.data[name] = value
+	return ":" + name
+}
+
+// column reports whether the column is a plain identifier recording the error if not.
+func (b *Builder) column(column string) bool {
+	if !identifier.MatchString(column) {
+		b.fail(fmt.Errorf("column %q is not a valid identifier", column))
+		return false
+	}
+	return true
+}
+
+// fail records the first error.
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// deref returns the value a pointer points to. It reports false for nil so the filter
+// is not applied.
+func deref(value interface{}) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	return v.Interface(), true
+}
+
+// contains reports whether the value is in the list.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdforte/go-service/business/sys/database"
+	"github.com/rdforte/go-service/foundation/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestBuilder(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	columns := map[string]string{"name": "name", "email": "email"}
+
+	tl.Describe("Building filtered queries")
+	{
+		tl.It("should bind every value as a named parameter and skip nil filters")
+		{
+			name := "50%"
+			since := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+			var role *string
+
+			query, data, err := database.NewBuilder("SELECT * FROM users").
+				Contains("name", &name).
+				Has("roles", role).
+				Where("date_created", database.OpGreaterOrEqual, &since).
+				After([]string{"email", "user_id"}, []interface{}{"bill@example.com", "1"}, true).
+				Where("email", database.OpNotEqual, "admin@example.com").
+				OrderBy("email", "desc", columns, "user_id").
+				Limit(10).
+				Query()
+			if err != nil {
+				tl.Failed("Should build the query", err)
+			}
+			tl.Success("Should build the query")
+
+			exp := "SELECT * FROM users WHERE name ILIKE :name AND date_created >= :date_created AND " +
+				"(email, user_id) < (:email, :user_id) AND email <> :email_2 ORDER BY email DESC, user_id DESC LIMIT :limit"
+			if query != exp {
+				tl.Failed("Should compose the clauses", fmt.Errorf("query[%s]", query))
+			}
+			tl.Success("Should compose the clauses")
+
+			if data["name"] != `%50\%%` || data["date_created"] != since || data["email_2"] != "admin@example.com" || data["limit"] != 10 {
+				tl.Failed("Should bind the values", fmt.Errorf("data[%v]", data))
+			}
+			tl.Success("Should bind the values")
+
+			// The query is logged even though there is no database to execute it.
+			var buf bytes.Buffer
+			log := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zap.InfoLevel)).Sugar()
+			db, err := database.Open(database.Config{Host: "127.0.0.1:1", DisableTLS: true})
+			if err != nil {
+				tl.Failed("Should open the database", err)
+			}
+			t.Cleanup(func() { db.Close() })
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			database.NamedExecContext(ctx, log, db, query, data)
+
+			if logged := buf.String(); strings.Contains(logged, "example.com") || !strings.Contains(logged, logger.Redacted) {
+				tl.Failed("Should redact every parameter of a sensitive column", fmt.Errorf("log[%s]", logged))
+			}
+			tl.Success("Should redact every parameter of a sensitive column")
+
+			if data["email_2"] != "admin@example.com" {
+				tl.Failed("Should leave the parameters as is", fmt.Errorf("data[%v]", data))
+			}
+			tl.Success("Should leave the parameters as is")
+
+			count, _, err := database.NewBuilder("SELECT * FROM users").Contains("name", &name).Limit(10).CountQuery()
+			if err != nil || count != "SELECT count(1) AS count FROM (SELECT * FROM users WHERE name ILIKE :name) AS filtered" {
+				tl.Failed("Should count the filtered rows", fmt.Errorf("query[%s] err[%v]", count, err))
+			}
+			tl.Success("Should count the filtered rows")
+		}
+
+		tl.It("should reject anything which could inject SQL")
+		{
+			tests := map[string]*database.Builder{
+				"unknown order by":  database.NewBuilder("SELECT * FROM users").OrderBy("password_hash", "ASC", columns),
+				"bad direction":     database.NewBuilder("SELECT * FROM users").OrderBy("name", "ASC; DROP TABLE users", columns),
+				"bad column":        database.NewBuilder("SELECT * FROM users").Where("name = name OR 1", database.OpEqual, "x"),
+				"bad operator":      database.NewBuilder("SELECT * FROM users").Where("name", database.Op("= 1 OR name ="), "x"),
+				"mismatched keyset": database.NewBuilder("SELECT * FROM users").After([]string{"name", "user_id"}, []interface{}{"x"}, false),
+			}
+
+			for name, b := range tests {
+				if _, _, err := b.Query(); err == nil {
+					tl.Failed("Should reject "+name, fmt.Errorf("no error"))
+				}
+				tl.Success("Should reject " + name)
+			}
+		}
+	}
+}
@@ -7,18 +7,20 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq" // Calls init function (sql driver)
-	"github.com/rdforte/go-service/foundation/web"
+	"github.com/rdforte/go-service/foundation/logger"
 	"go.uber.org/zap"
 )
 
 // Set of error variables for CRUD operations
 var (
 	ErrDBNotFound = errors.New("not found")
+	ErrDBConflict = errors.New("conflict")
 )
 
 // Config is the required properties to use the database.
@@ -103,9 +105,9 @@ func NamedExecContext(
 	db *sqlx.DB,
 	query string,
 	data interface{},
-) error {
-	q := queryString(query, data)
-	log.Infow("database.NamedExecContext", "traceid", web.GetTraceID(ctx), "query", q)
+) (err error) {
+	name, start := queryName(), time.Now()
+	defer func() { err = observe(ctx, log, db, "database.NamedExecContext", name, query, data, start, err) }()
 
 	if _, err := db.NamedExecContext(ctx, query, data); err != nil {
 		return err
@@ -114,6 +116,28 @@ func NamedExecContext(
 	return nil
 }
 
+// NamedExecContextAffected is a helper function for executing a CUD operation like
+// NamedExecContext which returns the number of rows affected.
+func NamedExecContextAffected(
+	ctx context.Context,
+	log *zap.SugaredLogger,
+	db *sqlx.DB,
+	query string,
+	data interface{},
+) (rows int64, err error) {
+	name, start := queryName(), time.Now()
+	defer func() {
+		err = observe(ctx, log, db, "database.NamedExecContextAffected", name, query, data, start, err)
+	}()
+
+	result, err := db.NamedExecContext(ctx, query, data)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 // NamedQuerySlice is a helper function for executing queries that return a
 // collection of data to be unmarshaled into a slice.
 func NamedQuerySlice(
@@ -123,9 +147,9 @@ func NamedQuerySlice(
 	query string,
 	data interface{},
 	dest interface{},
-) error {
-	q := queryString(query, data)
-	log.Infow("database.NamedQuerySlice", "traceid", web.GetTraceID(ctx), "query", q)
+) (err error) {
+	name, start := queryName(), time.Now()
+	defer func() { err = observe(ctx, log, sqlxDB, "database.NamedQuerySlice", name, query, data, start, err) }()
 
 	val := reflect.ValueOf(dest)
 	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
@@ -136,6 +160,7 @@ func NamedQuerySlice(
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
 	slice := val.Elem()
 	for rows.Next() {
@@ -146,7 +171,41 @@ func NamedQuerySlice(
 		slice.Set(reflect.Append(slice, v.Elem()))
 	}
 
-	return nil
+	return rows.Err()
+}
+
+// NamedQueryIter is a helper function for executing queries that return a large
+// collection of data, such as exports, which is streamed rather than loaded into
+// a slice. Each row is unmarshaled into a new value of T and passed to fn. The
+// iteration stops at the first error returned by fn which is then returned.
+func NamedQueryIter[T any](
+	ctx context.Context,
+	log *zap.SugaredLogger,
+	sqlxDB *sqlx.DB,
+	query string,
+	data interface{},
+	fn func(T) error,
+) (err error) {
+	name, start := queryName(), time.Now()
+	defer func() { err = observe(ctx, log, sqlxDB, "database.NamedQueryIter", name, query, data, start, err) }()
+
+	rows, err := sqlxDB.NamedQueryContext(ctx, query, data)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v T
+		if err := rows.StructScan(&v); err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
 }
 
 // NamedQueryStruct is a helper function for executing queries that return a
@@ -158,15 +217,22 @@ func NamedQueryStruct(
 	query string,
 	data interface{},
 	dest interface{},
-) error {
-	q := queryString(query, data)
-	log.Infow("database.NamedQueryStruct", "traceid", web.GetTraceID(ctx), "query", q)
+) (err error) {
+	name, start := queryName(), time.Now()
+	defer func() { err = observe(ctx, log, sqlxDB, "database.NamedQueryStruct", name, query, data, start, err) }()
 
 	rows, err := sqlxDB.NamedQueryContext(ctx, query, data)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
+
 	if !rows.Next() {
+
+		// A failure fetching the first row is not the same as no rows.
+		if err := rows.Err(); err != nil {
+			return err
+		}
 		return ErrDBNotFound
 	}
 
@@ -174,12 +240,13 @@ func NamedQueryStruct(
 		return err
 	}
 
-	return nil
+	return rows.Close()
 }
 
-// queryString provides a pretty print version of the query and parameters.
-func queryString(query string, args ...interface{}) string {
-	query, params, err := sqlx.Named(query, args)
+// queryString provides a pretty print version of the query and parameters. The values of
+// sensitive parameters are redacted so they never reach the logs.
+func queryString(query string, data interface{}) string {
+	query, params, err := sqlx.Named(query, redactNumbered(logger.Redact(data, "db")))
 	if err != nil {
 		return err.Error()
 	}
@@ -202,3 +269,26 @@ func queryString(query string, args ...interface{}) string {
 
 	return strings.Trim(query, " ")
 }
+
+// redactNumbered redacts the parameters the Builder numbered (email_2) when the column they
+// are named after is redacted.
+func redactNumbered(data interface{}) interface{} {
+	params, ok := data.(map[string]interface{})
+	if !ok {
+		return data
+	}
+
+	for name := range params {
+		i := strings.LastIndex(name, "_")
+		if i <= 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(name[i+1:]); err != nil {
+			continue
+		}
+		if logger.IsRedacted(name[:i]) {
+			params[name] = logger.Redacted
+		}
+	}
+	return params
+}
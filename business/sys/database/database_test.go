@@ -0,0 +1,192 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rdforte/go-service/business/sys/database"
+	"github.com/rdforte/go-service/foundation/logger"
+	"github.com/rdforte/go-service/foundation/web"
+	"go.uber.org/zap"
+)
+
+func TestMarkIntegrity(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Marking the errors of the database which put its integrity in doubt")
+	{
+		tests := []struct {
+			name      string
+			err       error
+			integrity bool
+		}{
+			{"data corrupted", &pq.Error{Code: "XX001"}, true},
+			{"index corrupted", fmt.Errorf("querying: %w", &pq.Error{Code: "XX002"}), true},
+			{"io error", &pq.Error{Code: "58030"}, true},
+			{"unique violation", &pq.Error{Code: "23505"}, false},
+			{"invalid text", &pq.Error{Code: "22P02"}, false},
+			{"not found", database.ErrDBNotFound, false},
+			{"other", errors.New("connection refused"), false},
+		}
+
+		for _, tt := range tests {
+			tl.It(fmt.Sprintf("should mark %s: %v", tt.name, tt.integrity))
+			{
+				err := database.MarkIntegrity(tt.err)
+				if web.IsIntegrity(err) != tt.integrity || !errors.Is(err, tt.err) {
+					tl.Failed("Should mark the error", fmt.Errorf("err[%v]", err))
+				}
+				tl.Success("Should mark the error")
+			}
+		}
+
+		tl.It("should leave no error as is")
+		{
+			if err := database.MarkIntegrity(nil); err != nil {
+				tl.Failed("Should return nil", err)
+			}
+			tl.Success("Should return nil")
+		}
+	}
+}
+
+func TestNamedQueryIter(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	type user struct {
+		ID   string `db:"user_id"`
+		Name string `db:"name"`
+	}
+
+	// iter streams the users returned by the fake result into fn.
+	iter := func(res *fakeResult, fn func(user) error) error {
+		db := sqlx.NewDb(sql.OpenDB(fakeConnector{res}), "postgres")
+		defer db.Close()
+
+		const q = `SELECT user_id, name FROM users WHERE name = :name`
+		return database.NamedQueryIter(context.Background(), zap.NewNop().Sugar(), db, q, map[string]interface{}{"name": "bill"}, fn)
+	}
+
+	tl.Describe("Streaming the rows of a query")
+	{
+		tl.It("should pass every row to fn and close the rows")
+		{
+			res := fakeResult{
+				columns: []string{"user_id", "name"},
+				rows:    [][]driver.Value{{"1", "bill"}, {"2", "jill"}, {"3", "jack"}},
+			}
+
+			var users []user
+			err := iter(&res, func(u user) error {
+				users = append(users, u)
+				return nil
+			})
+			if err != nil || fmt.Sprint(users) != "[{1 bill} {2 jill} {3 jack}]" || !res.closed {
+				tl.Failed("Should stream the rows", fmt.Errorf("users%v closed[%v] err[%v]", users, res.closed, err))
+			}
+			tl.Success("Should stream the rows")
+		}
+
+		tl.It("should return an error ending the iteration")
+		{
+			res := fakeResult{
+				columns: []string{"user_id", "name"},
+				rows:    [][]driver.Value{{"1", "bill"}, {"2", "jill"}},
+				err:     &pq.Error{Code: "XX001", Message: "invalid page in block"},
+			}
+
+			var calls int
+			err := iter(&res, func(u user) error {
+				calls++
+				return nil
+			})
+			var pqErr *pq.Error
+			if !errors.As(err, &pqErr) || !web.IsIntegrity(err) || calls != 2 || !res.closed {
+				tl.Failed("Should surface the error of the rows", fmt.Errorf("calls[%d] closed[%v] err[%v]", calls, res.closed, err))
+			}
+			tl.Success("Should surface the error of the rows")
+		}
+
+		tl.It("should stop at the first error of fn and close the rows")
+		{
+			res := fakeResult{
+				columns: []string{"user_id", "name"},
+				rows:    [][]driver.Value{{"1", "bill"}, {"2", "jill"}},
+			}
+
+			errStop := errors.New("export failed")
+			var calls int
+			err := iter(&res, func(u user) error {
+				calls++
+				return errStop
+			})
+			if !errors.Is(err, errStop) || calls != 1 || !res.closed {
+				tl.Failed("Should return the error of fn", fmt.Errorf("calls[%d] closed[%v] err[%v]", calls, res.closed, err))
+			}
+			tl.Success("Should return the error of fn")
+		}
+	}
+}
+
+// =============================================================================
+// A database/sql driver returning a fixed result to every query.
+
+// fakeResult is the result of every query. The err is returned once the rows are read and
+// closed reports whether the rows were closed.
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error
+	closed  bool
+}
+
+type fakeConnector struct{ res *fakeResult }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ res *fakeResult }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(c), nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct{ res *fakeResult }
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{res: s.res}, nil
+}
+
+type fakeRows struct {
+	res  *fakeResult
+	next int
+}
+
+func (r *fakeRows) Columns() []string { return r.res.columns }
+func (r *fakeRows) Close() error {
+	r.res.closed = true
+	return nil
+}
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next == len(r.res.rows) {
+		if r.res.err != nil {
+			return r.res.err
+		}
+		return io.EOF
+	}
+	copy(dest, r.res.rows[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rdforte/go-service/business/sys/metrics"
+	"github.com/rdforte/go-service/foundation/web"
+	"go.uber.org/zap"
+)
+
+// SlowQuery configures how the queries taking too long are reported.
+type SlowQuery struct {
+
+	// Threshold logs the queries taking longer than it at warn level. Zero disables it.
+	Threshold time.Duration
+
+	// Explain logs the plan of the slow queries. It runs an extra EXPLAIN, without ANALYZE so
+	// the query is not executed again, and must not be enabled in production.
+	Explain bool
+}
+
+// slowQuery holds the SlowQuery configuration set for the service.
+var slowQuery atomic.Pointer[SlowQuery]
+
+// SetSlowQuery sets how slow queries are reported. It is called once during startup.
+func SetSlowQuery(sq SlowQuery) {
+	slowQuery.Store(&sq)
+}
+
+// observe logs the executed query with its duration, at warn level when it is slow, and
+// records its metrics under the name of the query. The error of the query is returned marked
+// by MarkIntegrity.
+func observe(ctx context.Context, log *zap.SugaredLogger, db *sqlx.DB, helper string, name string, query string, data interface{}, start time.Time, err error) error {
+	duration := time.Since(start)
+	err = MarkIntegrity(err)
+
+	// A missing row is an expected outcome rather than a failure of the query.
+	metrics.AddQuery(name, duration, err != nil && !errors.Is(err, ErrDBNotFound))
+
+	kv := []interface{}{
+		"traceid", web.GetTraceID(ctx),
+		"name", name,
+		"query", queryString(query, data),
+		"duration", duration,
+	}
+
+	sq := slowQuery.Load()
+	if sq == nil || sq.Threshold <= 0 || duration <= sq.Threshold {
+		log.Infow(helper, kv...)
+		return err
+	}
+
+	if sq.Explain {
+		plan, err := explain(ctx, db, query, data)
+		if err != nil {
+			kv = append(kv, "explainError", err)
+		} else {
+			kv = append(kv, "plan", plan)
+		}
+	}
+
+	log.Warnw("slow query", kv...)
+	return err
+}
+
+// integrityClasses are the classes of the errors the database reports when its data or
+// storage can no longer be trusted: internal errors such as data_corrupted and system errors
+// such as io_error.
+var integrityClasses = map[pq.ErrorClass]bool{
+	"XX": true,
+	"58": true,
+}
+
+// MarkIntegrity marks an error of the database reporting corruption as an integrity error so
+// it counts towards the shutdown policy of the App. Any other error is returned as is. The
+// helpers of this package mark their errors, stores querying the database directly must too.
+func MarkIntegrity(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && integrityClasses[pqErr.Code.Class()] {
+		return web.NewIntegrityError(err)
+	}
+	return err
+}
+
+// explain returns the plan the database chose for the query.
+func explain(ctx context.Context, db *sqlx.DB, query string, data interface{}) (string, error) {
+	q, args, err := sqlx.Named(query, data)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := db.QueryContext(ctx, "EXPLAIN "+db.Rebind(q), args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		plan = append(plan, line)
+	}
+
+	return strings.Join(plan, "\n"), rows.Err()
+}
+
+// queryNames caches the name of the function calling a helper by its program counter.
+var queryNames sync.Map
+
+// queryName returns the name of the function calling the helper, which is the store method
+// executing the query ie: user/db.Store.QueryByID. It must be called directly by the helper.
+func queryName() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return "unknown"
+	}
+
+	if name, ok := queryNames.Load(pc); ok {
+		return name.(string)
+	}
+
+	name := "unknown"
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		name = fn.Name()
+
+		// Keep the last two elements of the package path so stores in packages with the
+		// same name are told apart.
+		parts := strings.Split(name, "/")
+		if len(parts) > 2 {
+			name = strings.Join(parts[len(parts)-2:], "/")
+		}
+	}
+
+	queryNames.Store(pc, name)
+	return name
+}
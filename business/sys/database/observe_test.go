@@ -0,0 +1,114 @@
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rdforte/go-service/business/sys/database"
+	"github.com/rdforte/go-service/foundation/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestObserve(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	res := fakeResult{
+		columns: []string{"name"},
+		rows:    [][]driver.Value{{"bill"}},
+	}
+	db := sqlx.NewDb(sql.OpenDB(fakeConnector{&res}), "postgres")
+	defer db.Close()
+
+	defer database.SetSlowQuery(database.SlowQuery{})
+
+	const q = `SELECT name FROM users WHERE name = :name`
+	data := map[string]interface{}{"name": "bill"}
+
+	// query runs the query through NamedQueryIter, taking at least the delay, and returns the
+	// lines it logged.
+	query := func(delay time.Duration) []map[string]interface{} {
+		var buf bytes.Buffer
+		core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zap.DebugLevel)
+
+		fn := func(v struct{ Name string }) error {
+			time.Sleep(delay)
+			return nil
+		}
+		if err := database.NamedQueryIter(context.Background(), zap.New(core).Sugar(), db, q, data, fn); err != nil {
+			tl.Failed("Should run the query", err)
+		}
+
+		var lines []map[string]interface{}
+		dec := json.NewDecoder(&buf)
+		for {
+			var line map[string]interface{}
+			if err := dec.Decode(&line); err != nil {
+				if !errors.Is(err, io.EOF) {
+					tl.Failed("Should decode the log line", err)
+				}
+				return lines
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	tl.Describe("Observing the queries of the helpers")
+	{
+		tl.It("should log queries slower than the threshold at warn level")
+		{
+			database.SetSlowQuery(database.SlowQuery{Threshold: time.Millisecond})
+
+			lines := query(5 * time.Millisecond)
+			if len(lines) != 1 || lines[0]["level"] != "warn" || lines[0]["msg"] != "slow query" {
+				tl.Failed("Should warn about the slow query", fmt.Errorf("lines%v", lines))
+			}
+			tl.Success("Should warn about the slow query")
+
+			lines = query(0)
+			if len(lines) != 1 || lines[0]["level"] != "info" || lines[0]["msg"] != "database.NamedQueryIter" {
+				tl.Failed("Should log a fast query at info level", fmt.Errorf("lines%v", lines))
+			}
+			tl.Success("Should log a fast query at info level")
+		}
+
+		tl.It("should not warn when the threshold is disabled")
+		{
+			database.SetSlowQuery(database.SlowQuery{})
+
+			lines := query(5 * time.Millisecond)
+			if len(lines) != 1 || lines[0]["level"] != "info" {
+				tl.Failed("Should log the query at info level", fmt.Errorf("lines%v", lines))
+			}
+			tl.Success("Should log the query at info level")
+		}
+
+		tl.It("should name the query after the function calling the helper")
+		{
+			lines := query(0)
+
+			// The helper is called by query, the first function literal of the test.
+			const name = "sys/database_test.TestObserve.func1"
+			if len(lines) != 1 || lines[0]["name"] != name {
+				tl.Failed("Should log the name of the query", fmt.Errorf("lines%v", lines))
+			}
+			tl.Success("Should log the name of the query")
+
+			qm := expvar.Get("queries").(*expvar.Map).Get(name)
+			if qm == nil {
+				tl.Failed("Should record the metrics of the query", fmt.Errorf("queries[%s]", expvar.Get("queries")))
+			}
+			tl.Success("Should record the metrics of the query")
+		}
+	}
+}
@@ -24,6 +24,7 @@ type metrics struct {
 	requests   *expvar.Int
 	errors     *expvar.Int
 	panics     *expvar.Int
+	unhandled  *expvar.Int
 }
 
 /**
@@ -37,6 +38,7 @@ func init() {
 		requests:   expvar.NewInt("requests"),
 		errors:     expvar.NewInt("errors"),
 		panics:     expvar.NewInt("panics"),
+		unhandled:  expvar.NewInt("unhandled"),
 	}
 }
 
@@ -80,3 +82,10 @@ func AddPanics(ctx context.Context) {
 		v.panics.Add(1)
 	}
 }
+
+// AddUnhandled increments the unhandled errors metric by 1.
+func AddUnhandled(ctx context.Context) {
+	if v, ok := ctx.Value(key).(*metrics); ok {
+		v.unhandled.Add(1)
+	}
+}
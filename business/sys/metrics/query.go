@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// queries holds the metrics of every database query keyed by the name of the query.
+var queries = expvar.NewMap("queries")
+
+// queriesMu serializes adding new queries to the map.
+var queriesMu sync.Mutex
+
+// latencyBuckets are the upper bounds of the query latency histogram.
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// AddQuery records the latency of the named database query and counts it as an error when it failed.
+// Queries are recorded without a context since they also run outside of requests.
+func AddQuery(name string, latency time.Duration, failed bool) {
+	v := queries.Get(name)
+	if v == nil {
+
+		// Another goroutine may be adding the same query in the meantime.
+		queriesMu.Lock()
+		if v = queries.Get(name); v == nil {
+			v = &queryMetrics{buckets: make([]int64, len(latencyBuckets))}
+			queries.Set(name, v)
+		}
+		queriesMu.Unlock()
+	}
+
+	qm, ok := v.(*queryMetrics)
+	if !ok {
+		return
+	}
+	qm.add(latency, failed)
+}
+
+// queryMetrics is the latency histogram and error count of a query. It is an expvar.Var so
+// it is published as JSON.
+type queryMetrics struct {
+	mu      sync.Mutex
+	count   int64
+	errors  int64
+	sum     time.Duration
+	buckets []int64
+}
+
+// add records a single execution of the query.
+func (qm *queryMetrics) add(latency time.Duration, failed bool) {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	qm.count++
+	qm.sum += latency
+	if failed {
+		qm.errors++
+	}
+
+	for i, bound := range latencyBuckets {
+		if latency <= bound {
+			qm.buckets[i]++
+			break
+		}
+	}
+}
+
+// String returns the metrics as JSON with the cumulative count of executions within each bucket.
+func (qm *queryMetrics) String() string {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	buckets := make(map[string]int64, len(latencyBuckets)+1)
+	var cumulative int64
+	for i, bound := range latencyBuckets {
+		cumulative += qm.buckets[i]
+		buckets[bound.String()] = cumulative
+	}
+	buckets["+Inf"] = qm.count
+
+	data, _ := json.Marshal(struct {
+		Count   int64            `json:"count"`
+		Errors  int64            `json:"errors"`
+		SumMS   float64          `json:"sum_ms"`
+		Buckets map[string]int64 `json:"buckets"`
+	}{
+		Count:   qm.count,
+		Errors:  qm.errors,
+		SumMS:   float64(qm.sum) / float64(time.Millisecond),
+		Buckets: buckets,
+	})
+	return string(data)
+}
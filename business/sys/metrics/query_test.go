@@ -0,0 +1,54 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rdforte/go-service/business/sys/metrics"
+	"github.com/rdforte/go-service/foundation/logger"
+)
+
+func TestAddQuery(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Recording the metrics of a query")
+	{
+		tl.It("should count the executions within each latency bucket")
+		{
+			const name = "user/db.Store.QueryByID"
+			for _, latency := range []time.Duration{500 * time.Microsecond, time.Millisecond, 3 * time.Millisecond, 2 * time.Second, 10 * time.Second} {
+				metrics.AddQuery(name, latency, latency > time.Second)
+			}
+
+			var qm struct {
+				Count   int64            `json:"count"`
+				Errors  int64            `json:"errors"`
+				SumMS   float64          `json:"sum_ms"`
+				Buckets map[string]int64 `json:"buckets"`
+			}
+			if err := json.Unmarshal([]byte(expvar.Get("queries").(*expvar.Map).Get(name).String()), &qm); err != nil {
+				tl.Failed("Should publish the metrics as JSON", err)
+			}
+			tl.Success("Should publish the metrics as JSON")
+
+			if qm.Count != 5 || qm.Errors != 2 || qm.SumMS != 12004.5 {
+				tl.Failed("Should count the executions and errors", fmt.Errorf("count[%d] errors[%d] sum[%v]", qm.Count, qm.Errors, qm.SumMS))
+			}
+			tl.Success("Should count the executions and errors")
+
+			exp := map[string]int64{
+				"1ms": 2, "5ms": 3, "10ms": 3, "25ms": 3, "50ms": 3, "100ms": 3,
+				"250ms": 3, "500ms": 3, "1s": 3, "5s": 4, "+Inf": 5,
+			}
+			for bucket, count := range exp {
+				if qm.Buckets[bucket] != count {
+					tl.Failed(fmt.Sprintf("Should count %d executions within %s", count, bucket), fmt.Errorf("buckets%v", qm.Buckets))
+				}
+			}
+			tl.Success("Should count the cumulative executions within each bucket")
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Package paging provides support for keyset pagination of queries. Clients page through
+// the results with opaque cursors so the position of a page is stable while rows are being
+// inserted and deleted, unlike page numbers.
+package paging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rdforte/go-service/business/sys/validate"
+)
+
+// Set of directions the results can be ordered in.
+const (
+	ASC  = "ASC"
+	DESC = "DESC"
+)
+
+// Limits on the number of items in a page.
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// CodeInvalidCursor is the stable error code of ErrInvalidCursor.
+const CodeInvalidCursor = "INVALID_CURSOR"
+
+// ErrInvalidCursor is returned when a cursor was not created for the query or its key is not
+// a valid value of the field, which means it was tampered with.
+var ErrInvalidCursor = validate.NewError(CodeInvalidCursor, "invalid cursor")
+
+// init registers the HTTP status and the translated messages for the paging error codes.
+func init() {
+	validate.RegisterStatus(CodeInvalidCursor, http.StatusBadRequest)
+
+	messages := map[string]string{
+		"fr": "curseur invalide",
+		"de": "ungültiger Cursor",
+		"es": "cursor no válido",
+	}
+
+	if err := validate.RegisterMessages(CodeInvalidCursor, messages); err != nil {
+		panic(err)
+	}
+}
+
+// OrderBy is the field and direction the results are ordered by. Ties are broken by the id
+// so the order is always total.
+type OrderBy struct {
+	Field     string
+	Direction string
+}
+
+// Cursor is the position of a page within the results. It is the key of the item at the edge
+// of the page the client has seen and whether the page before or after it is wanted.
+type Cursor struct {
+	Field  string `json:"f"`
+	Value  string `json:"v"`
+	ID     string `json:"id"`
+	Before bool   `json:"b,omitempty"`
+}
+
+// Encode returns the opaque representation of the cursor handed to clients.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the cursor of the opaque representation handed to clients.
+func DecodeCursor(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// Request describes the page of results which is wanted.
+type Request struct {
+	OrderBy OrderBy
+	Limit   int
+	Cursor  *Cursor
+}
+
+// Descending reports whether the rows must be fetched in descending order. The order of the
+// request is reversed when fetching the page before the cursor.
+func (r Request) Descending() bool {
+	desc := r.OrderBy.Direction == DESC
+	if r.Cursor != nil && r.Cursor.Before {
+		return !desc
+	}
+	return desc
+}
+
+// Parse reads the request from the cursor, limit and order_by query parameters. The order_by
+// parameter is a field optionally followed by the direction, ie: name,DESC. Only the provided
+// fields may be ordered by and the cursor must have been created for the same field.
+func Parse(values url.Values, fields []string, def OrderBy) (Request, error) {
+	req := Request{
+		OrderBy: def,
+		Limit:   DefaultLimit,
+	}
+
+	if v := values.Get("order_by"); v != "" {
+		field, direction, _ := strings.Cut(v, ",")
+		direction = strings.ToUpper(strings.TrimSpace(direction))
+
+		if !contains(fields, field) {
+			return Request{}, fmt.Errorf("order_by field %q is not one of %s", field, strings.Join(fields, ", "))
+		}
+
+		switch direction {
+		case "":
+			direction = ASC
+		case ASC, DESC:
+		default:
+			return Request{}, fmt.Errorf("order_by direction %q must be %s or %s", direction, ASC, DESC)
+		}
+
+		req.OrderBy = OrderBy{Field: field, Direction: direction}
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return Request{}, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+		req.Limit = limit
+	}
+
+	if v := values.Get("cursor"); v != "" {
+		c, err := DecodeCursor(v)
+		if err != nil {
+			return Request{}, err
+		}
+		if c.Field != req.OrderBy.Field {
+			return Request{}, ErrInvalidCursor
+		}
+		req.Cursor = &c
+	}
+
+	return req, nil
+}
+
+// Page is the envelope of a page of results. Next and Prev are the cursors of the adjacent
+// pages and are empty when there is no such page.
+type Page[T any] struct {
+	Items []T    `json:"items"`
+	Total int    `json:"total"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+}
+
+// NewPage builds the page from the rows fetched for the request. The rows must be fetched in
+// the order reported by Request.Descending with a limit of one more than the request so it is
+// known whether more rows follow. The key function returns the value of the ordered field and
+// the id of an item.
+func NewPage[T any](req Request, rows []T, total int, key func(T) (value string, id string)) Page[T] {
+	more := len(rows) > req.Limit
+	if more {
+		rows = rows[:req.Limit]
+	}
+
+	before := req.Cursor != nil && req.Cursor.Before
+	if before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	page := Page[T]{
+		Items: rows,
+		Total: total,
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(rows) == 0 {
+		return page
+	}
+
+	cursor := func(item T, before bool) string {
+		value, id := key(item)
+		return Cursor{Field: req.OrderBy.Field, Value: value, ID: id, Before: before}.Encode()
+	}
+
+	// Going forward there are rows before the page when it started after a cursor and going
+	// backwards there are always rows after the page.
+	switch {
+	case before:
+		page.Next = cursor(rows[len(rows)-1], false)
+		if more {
+			page.Prev = cursor(rows[0], true)
+		}
+	default:
+		if more {
+			page.Next = cursor(rows[len(rows)-1], false)
+		}
+		if req.Cursor != nil {
+			page.Prev = cursor(rows[0], true)
+		}
+	}
+
+	return page
+}
+
+// contains reports whether the value is in the list.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
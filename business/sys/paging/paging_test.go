@@ -0,0 +1,101 @@
+package paging_test
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/rdforte/go-service/business/sys/paging"
+	"github.com/rdforte/go-service/foundation/logger"
+)
+
+func TestParse(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	fields := []string{"id", "name"}
+	def := paging.OrderBy{Field: "id", Direction: paging.ASC}
+
+	tl.Describe("Parsing the page request from the query string")
+	{
+		tl.It("should apply the defaults and read the order by, limit and cursor")
+		{
+			req, err := paging.Parse(url.Values{}, fields, def)
+			if err != nil || req.OrderBy != def || req.Limit != paging.DefaultLimit || req.Cursor != nil {
+				tl.Failed("Should apply the defaults", fmt.Errorf("req[%+v] err[%v]", req, err))
+			}
+			tl.Success("Should apply the defaults")
+
+			cursor := paging.Cursor{Field: "name", Value: "bill", ID: "1"}.Encode()
+			values := url.Values{"order_by": {"name,desc"}, "limit": {"5"}, "cursor": {cursor}}
+
+			req, err = paging.Parse(values, fields, def)
+			if err != nil || req.OrderBy.Field != "name" || req.OrderBy.Direction != paging.DESC || req.Limit != 5 || req.Cursor == nil || req.Cursor.Value != "bill" {
+				tl.Failed("Should read the request", fmt.Errorf("req[%+v] err[%v]", req, err))
+			}
+			tl.Success("Should read the request")
+		}
+
+		tl.It("should reject fields which are not whitelisted and invalid cursors")
+		{
+			tests := map[string]url.Values{
+				"unknown field":   {"order_by": {"password_hash"}},
+				"bad direction":   {"order_by": {"name,sideways"}},
+				"limit too large": {"limit": {"1000"}},
+				"garbage cursor":  {"cursor": {"not a cursor"}},
+				"cursor of other field": {
+					"cursor": {paging.Cursor{Field: "name", Value: "bill", ID: "1"}.Encode()},
+				},
+			}
+
+			for name, values := range tests {
+				if _, err := paging.Parse(values, fields, def); err == nil {
+					tl.Failed("Should reject "+name, errors.New("no error"))
+				}
+				tl.Success("Should reject " + name)
+			}
+		}
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	key := func(v string) (string, string) { return v, v }
+	orderBy := paging.OrderBy{Field: "id", Direction: paging.ASC}
+
+	tl.Describe("Building a page from the fetched rows")
+	{
+		tl.It("should link to the next page when more rows were fetched than the limit")
+		{
+			req := paging.Request{OrderBy: orderBy, Limit: 2}
+			page := paging.NewPage(req, []string{"a", "b", "c"}, 5, key)
+
+			if fmt.Sprint(page.Items) != "[a b]" || page.Total != 5 || page.Prev != "" {
+				tl.Failed("Should trim the rows to the limit", fmt.Errorf("page[%+v]", page))
+			}
+			tl.Success("Should trim the rows to the limit")
+
+			next, err := paging.DecodeCursor(page.Next)
+			if err != nil || next.ID != "b" || next.Before {
+				tl.Failed("Should continue after the last item", fmt.Errorf("cursor[%+v] err[%v]", next, err))
+			}
+			tl.Success("Should continue after the last item")
+		}
+
+		tl.It("should reverse the rows fetched before a cursor")
+		{
+			req := paging.Request{OrderBy: orderBy, Limit: 2, Cursor: &paging.Cursor{Field: "id", ID: "d", Before: true}}
+			if !req.Descending() {
+				tl.Failed("Should fetch in the reverse order", errors.New("ascending"))
+			}
+			tl.Success("Should fetch in the reverse order")
+
+			page := paging.NewPage(req, []string{"c", "b"}, 5, key)
+			if fmt.Sprint(page.Items) != "[b c]" || page.Next == "" || page.Prev != "" {
+				tl.Failed("Should be the first page", fmt.Errorf("page[%+v]", page))
+			}
+			tl.Success("Should be the first page")
+		}
+	}
+}
@@ -4,19 +4,53 @@ package validate
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Set of stable error codes shared across the core packages.
+const (
+	CodeInternal   = "INTERNAL"
+	CodeValidation = "VALIDATION_FAILED"
+	CodeInvalidID  = "INVALID_ID"
+	CodeForbidden  = "FORBIDDEN"
 )
 
 var (
-	ErrInvalidID = errors.New("ID is not in its proper form")
-	ErrForbidden = errors.New("attempted action is not allowed")
+	ErrInvalidID = NewError(CodeInvalidID, "ID is not in its proper form")
+	ErrForbidden = NewError(CodeForbidden, "attempted action is not allowed")
 )
 
-// ErrorResponse is the format used for API responses from failures in the API.
+// ProblemContentType is the media type used for RFC 7807 problem detail responses.
+const ProblemContentType = "application/problem+json"
+
+// ErrorResponse is the legacy format used for API responses from failures in the API.
+// The misspelt `fileds` key is part of the v1 contract and must not be corrected.
 type ErrorResponse struct {
 	Error  string `json:"error"`
+	Code   string `json:"code"`
 	Fields string `json:"fileds,omitempty"`
 }
 
+/**
+ProblemDetail is the RFC 7807 (application/problem+json) format used for API responses from failures
+in the API. Field errors are returned as a structured array so clients don't have to decode them twice.
+https://datatracker.ietf.org/doc/html/rfc7807
+*/
+type ProblemDetail struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
 /**
 Request Error is used to pass an error during the request through the application
 with web specific context
@@ -43,17 +77,102 @@ func NewRequestError(err error, status int) error {
 	return &RequestError{err, status, nil}
 }
 
+// Error is a trusted error carrying a stable, machine readable code (ie: USER_NOT_FOUND) which
+// clients can rely on regardless of how the message is worded.
+type Error struct {
+	Code string
+	Err  error
+}
+
+// Error implements the error interface.
+func (err *Error) Error() string {
+	return err.Err.Error()
+}
+
+// NewError constructs an Error for the code and message. Core packages declare their sentinel
+// errors with NewError so they can still be compared using errors.Is.
+func NewError(code string, message string) error {
+	return &Error{code, errors.New(message)}
+}
+
+// statuses is the registry mapping error codes to the HTTP status they are responded with.
+var statuses = struct {
+	sync.RWMutex
+	m map[string]int
+}{
+	m: map[string]int{
+		CodeValidation: http.StatusBadRequest,
+		CodeInvalidID:  http.StatusBadRequest,
+		CodeForbidden:  http.StatusForbidden,
+	},
+}
+
+// RegisterStatus maps an error code to the HTTP status used when responding with it.
+func RegisterStatus(code string, status int) {
+	statuses.Lock()
+	defer statuses.Unlock()
+
+	statuses.m[code] = status
+}
+
+// StatusOf returns the HTTP status registered for the error code.
+func StatusOf(code string) (int, bool) {
+	statuses.RLock()
+	defer statuses.RUnlock()
+
+	status, ok := statuses.m[code]
+	return status, ok
+}
+
+// Code returns the code for a RequestError. The code of a wrapped Error takes precedence,
+// otherwise one is derived from the status text ie: 404 returns NOT_FOUND.
+func (err *RequestError) Code() string {
+	var coded *Error
+	if errors.As(err.Err, &coded) {
+		return coded.Code
+	}
+	return strings.ToUpper(strings.ReplaceAll(http.StatusText(err.Status), " ", "_"))
+}
+
 // FieldError is used to indicate an error with a specific request field
 type FieldError struct {
 	Field string `json:"field"`
 	Error string `json:"error"`
+
+	// verror is the validator error the message was translated from. It is kept so the
+	// message can be translated again for the locale of the request.
+	verror validator.FieldError
 }
 
 // FieldErrors represents a collection of field errors.
 type FieldErrors []FieldError
 
-// Error implements the error interface
+// Translate returns a copy of the field errors with the messages translated into the locale.
+// Messages without a translation for the locale fall back to the default locale.
+func (fe FieldErrors) Translate(locale string) FieldErrors {
+	fields := make(FieldErrors, len(fe))
+	for i, field := range fe {
+		if field.verror != nil {
+			field.Error = translateField(field.verror, locale)
+		}
+		fields[i] = field
+	}
+	return fields
+}
+
+// Error implements the error interface. The field errors are joined into a single human
+// readable message, use JSON for the legacy serialized form.
 func (fe FieldErrors) Error() string {
+	msgs := make([]string, len(fe))
+	for i, field := range fe {
+		msgs[i] = fmt.Sprintf("%s: %s", field.Field, field.Error)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// JSON returns the field errors serialized as a JSON string. This is the form used by the
+// legacy ErrorResponse.
+func (fe FieldErrors) JSON() string {
 	d, err := json.Marshal(fe)
 	if err != nil {
 		return err.Error()
@@ -0,0 +1,123 @@
+package validate
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/rdforte/go-service/business/sys/auth"
+)
+
+// Set of project specific validation tags registered by default.
+const (
+	TagID       = "id"
+	TagPassword = "password"
+	TagRole     = "role"
+)
+
+// Password policy enforced by the password tag. bcrypt only uses the first 72 bytes
+// of a password so anything longer is rejected rather than silently truncated.
+const (
+	passwordMinLength = 8
+	passwordMaxLength = 72
+)
+
+// registerRules registers the project specific validation tags.
+func registerRules() {
+	rules := []struct {
+		tag      string
+		fn       validator.Func
+		messages map[string]string
+	}{
+		{
+			tag: TagID,
+			fn:  isID,
+			messages: map[string]string{
+				"en": "{0} must be a valid ID",
+				"fr": "{0} doit être un identifiant valide",
+				"de": "{0} muss eine gültige ID sein",
+				"es": "{0} debe ser un ID válido",
+			},
+		},
+		{
+			tag: TagPassword,
+			fn:  isPassword,
+			messages: map[string]string{
+				"en": "{0} must be between 8 and 72 characters and contain at least one letter and one number",
+				"fr": "{0} doit contenir entre 8 et 72 caractères dont au moins une lettre et un chiffre",
+				"de": "{0} muss zwischen 8 und 72 Zeichen lang sein und mindestens einen Buchstaben und eine Zahl enthalten",
+				"es": "{0} debe tener entre 8 y 72 caracteres y contener al menos una letra y un número",
+			},
+		},
+		{
+			tag: TagRole,
+			fn:  isRole,
+			messages: map[string]string{
+				"en": "{0} must be a valid role",
+				"fr": "{0} doit être un rôle valide",
+				"de": "{0} muss eine gültige Rolle sein",
+				"es": "{0} debe ser un rol válido",
+			},
+		},
+	}
+
+	for _, rule := range rules {
+		if err := RegisterRule(rule.tag, rule.fn, rule.messages); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterRule adds a custom validation tag along with the messages, keyed by locale, used when
+// the validation fails. A message may reference the field name with {0} and the tag parameter
+// with {1}. Rules must be registered before any validation happens ie: from an init function.
+func RegisterRule(tag string, fn validator.Func, messages map[string]string) error {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		return fmt.Errorf("registering validation tag[%s]: %w", tag, err)
+	}
+
+	for locale, message := range messages {
+		trans, ok := translators[locale]
+		if !ok {
+			return fmt.Errorf("registering translation tag[%s]: unsupported locale[%s]", tag, locale)
+		}
+
+		if err := validate.RegisterTranslation(tag, trans, addTranslation(tag, message), translateParams); err != nil {
+			return fmt.Errorf("registering translation tag[%s] locale[%s]: %w", tag, locale, err)
+		}
+	}
+
+	return nil
+}
+
+// isID validates that the field is a valid entity ID.
+func isID(fl validator.FieldLevel) bool {
+	_, err := uuid.Parse(fl.Field().String())
+	return err == nil
+}
+
+// isPassword validates that the field satisfies the password policy.
+func isPassword(fl validator.FieldLevel) bool {
+	pass := fl.Field().String()
+	if len(pass) < passwordMinLength || len(pass) > passwordMaxLength {
+		return false
+	}
+
+	var letter, number bool
+	for _, r := range pass {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsNumber(r):
+			number = true
+		}
+	}
+
+	return letter && number
+}
+
+// isRole validates that the field is one of the roles a user can be assigned.
+func isRole(fl validator.FieldLevel) bool {
+	return auth.ValidRole(fl.Field().String())
+}
@@ -0,0 +1,261 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// DefaultLocale is the locale used when none of the requested locales are supported.
+const DefaultLocale = "en"
+
+// supportedLocales is the set of locales we bundle translations for.
+var supportedLocales = []string{"en", "fr", "de", "es"}
+
+// Locale returns the best supported locale for the value of an Accept-Language header. Each
+// requested language is tried in order of preference, first as provided (fr-CA) then by its
+// base language (fr). When nothing matches the DefaultLocale is returned.
+func Locale(acceptLanguage string) string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var langs []language
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		if len(fields) > 1 && strings.HasPrefix(strings.TrimSpace(fields[1]), "q=") {
+			v, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(fields[1]), "q="), 64)
+			if err != nil {
+				continue
+			}
+			quality = v
+		}
+
+		// A quality of 0 means the language is not acceptable.
+		if quality <= 0 {
+			continue
+		}
+
+		langs = append(langs, language{strings.ToLower(strings.ReplaceAll(tag, "_", "-")), quality})
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].quality > langs[j].quality
+	})
+
+	for _, lang := range langs {
+		if _, ok := translators[lang.tag]; ok {
+			return lang.tag
+		}
+
+		base := strings.SplitN(lang.tag, "-", 2)[0]
+		if _, ok := translators[base]; ok {
+			return base
+		}
+	}
+
+	return DefaultLocale
+}
+
+// Message returns the message registered for the error code in the locale. When the locale
+// has no message the DefaultLocale is tried before returning the fallback.
+func Message(locale string, code string, fallback string) string {
+	for _, l := range []string{locale, DefaultLocale} {
+		if trans, ok := translators[l]; ok {
+			if msg, err := trans.T(code); err == nil {
+				return msg
+			}
+		}
+	}
+	return fallback
+}
+
+// RegisterMessages adds the messages, keyed by locale, for an error code. The english message
+// of an Error is used when a locale has no message so it does not need to be registered.
+func RegisterMessages(code string, messages map[string]string) error {
+	for locale, message := range messages {
+		trans, ok := translators[locale]
+		if !ok {
+			return fmt.Errorf("registering message code[%s]: unsupported locale[%s]", code, locale)
+		}
+
+		if err := trans.Add(code, message, true); err != nil {
+			return fmt.Errorf("registering message code[%s] locale[%s]: %w", code, locale, err)
+		}
+	}
+	return nil
+}
+
+// registerMessages registers the translated messages for the error codes of this package.
+func registerMessages() {
+	messages := map[string]map[string]string{
+		CodeInternal: {
+			"fr": "Erreur interne du serveur",
+			"de": "Interner Serverfehler",
+			"es": "Error interno del servidor",
+		},
+		CodeValidation: {
+			"fr": "erreur de validation des données",
+			"de": "Fehler bei der Datenvalidierung",
+			"es": "error de validación de datos",
+		},
+		CodeInvalidID: {
+			"fr": "l'identifiant n'est pas dans un format valide",
+			"de": "die ID hat kein gültiges Format",
+			"es": "el ID no tiene el formato correcto",
+		},
+		CodeForbidden: {
+			"fr": "l'action tentée n'est pas autorisée",
+			"de": "die versuchte Aktion ist nicht erlaubt",
+			"es": "la acción intentada no está permitida",
+		},
+	}
+
+	for code, msgs := range messages {
+		if err := RegisterMessages(code, msgs); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// translateField translates the validator error into the locale. The validator returns the raw
+// error when a tag has no translation so that is used to detect when to fall back.
+func translateField(verror validator.FieldError, locale string) string {
+	if trans, ok := translators[locale]; ok {
+		if msg := verror.Translate(trans); msg != verror.Error() {
+			return msg
+		}
+	}
+	return verror.Translate(translator)
+}
+
+// registerGermanTranslations registers german messages for the validator tags we rely on. Tags
+// without a german message fall back to the DefaultLocale.
+func registerGermanTranslations(v *validator.Validate, trans ut.Translator) {
+	translations := map[string]string{
+		"required": "{0} ist ein Pflichtfeld",
+		"email":    "{0} muss eine gültige E-Mail-Adresse sein",
+		"eqfield":  "{0} muss gleich {1} sein",
+		"nefield":  "{0} darf nicht gleich {1} sein",
+		"oneof":    "{0} muss einer der folgenden Werte sein: [{1}]",
+		"uuid":     "{0} muss eine gültige UUID sein",
+		"url":      "{0} muss eine gültige URL sein",
+	}
+
+	for tag, message := range translations {
+		if err := v.RegisterTranslation(tag, trans, addTranslation(tag, message), translateParams); err != nil {
+			panic(err)
+		}
+	}
+
+	// The size tags compare the length of strings, the number of items of slices and maps and
+	// the value of numbers, so each has a message per kind of field.
+	sizeTranslations := map[string]sizeMessages{
+		"len": {
+			str:    "{0} muss {1} Zeichen lang sein",
+			number: "{0} muss gleich {1} sein",
+			items:  "die Anzahl der Elemente von {0} muss {1} sein",
+		},
+		"min": {
+			str:    "{0} muss mindestens {1} Zeichen lang sein",
+			number: "{0} muss {1} oder größer sein",
+			items:  "die Anzahl der Elemente von {0} muss mindestens {1} sein",
+		},
+		"max": {
+			str:    "{0} darf höchstens {1} Zeichen lang sein",
+			number: "{0} muss {1} oder kleiner sein",
+			items:  "die Anzahl der Elemente von {0} darf höchstens {1} sein",
+		},
+		"gt": {
+			str:    "{0} muss länger als {1} Zeichen sein",
+			number: "{0} muss größer als {1} sein",
+			items:  "die Anzahl der Elemente von {0} muss größer als {1} sein",
+		},
+		"gte": {
+			str:    "{0} muss mindestens {1} Zeichen lang sein",
+			number: "{0} muss {1} oder größer sein",
+			items:  "die Anzahl der Elemente von {0} muss mindestens {1} sein",
+		},
+		"lt": {
+			str:    "{0} muss kürzer als {1} Zeichen sein",
+			number: "{0} muss kleiner als {1} sein",
+			items:  "die Anzahl der Elemente von {0} muss kleiner als {1} sein",
+		},
+		"lte": {
+			str:    "{0} darf höchstens {1} Zeichen lang sein",
+			number: "{0} muss {1} oder kleiner sein",
+			items:  "die Anzahl der Elemente von {0} darf höchstens {1} sein",
+		},
+	}
+
+	for tag, messages := range sizeTranslations {
+		if err := v.RegisterTranslation(tag, trans, messages.add(tag), translateSize); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// sizeMessages are the messages of a size tag for each kind of field.
+type sizeMessages struct {
+	str    string
+	number string
+	items  string
+}
+
+// add returns a function registering the messages for the tag with a translator.
+func (m sizeMessages) add(tag string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		for kind, message := range map[string]string{"string": m.str, "number": m.number, "items": m.items} {
+			if err := trans.Add(tag+"-"+kind, message, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// translateSize translates a field error of a size tag with the message for the kind of field.
+func translateSize(trans ut.Translator, fe validator.FieldError) string {
+	kind := "number"
+	switch fe.Kind() {
+	case reflect.String:
+		kind = "string"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		kind = "items"
+	}
+
+	msg, err := trans.T(fe.Tag()+"-"+kind, fe.Field(), fe.Param())
+	if err != nil {
+		return fe.Error()
+	}
+	return msg
+}
+
+// addTranslation returns a function registering the message for the tag with a translator.
+func addTranslation(tag string, message string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(tag, message, true)
+	}
+}
+
+// translateParams translates a field error providing the field name as {0} and the tag
+// parameter as {1}.
+func translateParams(trans ut.Translator, fe validator.FieldError) string {
+	msg, err := trans.T(fe.Tag(), fe.Field(), fe.Param())
+	if err != nil {
+		return fe.Error()
+	}
+	return msg
+}
@@ -2,32 +2,52 @@
 package validate
 
 import (
+	"errors"
 	"reflect"
 	"strings"
 
+	"github.com/go-playground/locales/de"
 	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	en_translations "github.com/go-playground/validator/v10/translations/en"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
+	"github.com/google/uuid"
 )
 
 // validate holds the settings and caches for validating request struct values.
 var validate *validator.Validate
 
-// translator is a cache of locale and translation information.
+// translator is a cache of locale and translation information for the default locale.
 var translator ut.Translator
 
+// translators holds the translator for every supported locale.
+var translators map[string]ut.Translator
+
 func init() {
 
 	// Instantiate a validator.
 	validate = validator.New()
 
-	// Create a translator for english so the error messages are
+	// Create a translator for every supported locale so the error messages are
 	// more human readable than technical.
-	translator, _ = ut.New(en.New(), en.New()).GetTranslator("en")
+	uni := ut.New(en.New(), en.New(), fr.New(), de.New(), es.New())
+
+	translators = make(map[string]ut.Translator, len(supportedLocales))
+	for _, locale := range supportedLocales {
+		translators[locale], _ = uni.GetTranslator(locale)
+	}
+	translator = translators[DefaultLocale]
 
-	// Register the english error messages for use.
-	en_translations.RegisterDefaultTranslations(validate, translator)
+	// Register the error messages for use. German is not bundled with the validator
+	// so we maintain our own.
+	en_translations.RegisterDefaultTranslations(validate, translators["en"])
+	fr_translations.RegisterDefaultTranslations(validate, translators["fr"])
+	es_translations.RegisterDefaultTranslations(validate, translators["es"])
+	registerGermanTranslations(validate, translators["de"])
 
 	// Use JSON tag names for errors instead of Go struct names.
 	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
@@ -37,6 +57,10 @@ func init() {
 		}
 		return name
 	})
+
+	// Register the project specific validation tags and error messages.
+	registerRules()
+	registerMessages()
 }
 
 // Check validates the provided model against it's declared tags.
@@ -52,8 +76,9 @@ func Check(val interface{}) error {
 		var fields FieldErrors
 		for _, verror := range verrors {
 			field := FieldError{
-				Field: verror.Field(),
-				Error: verror.Translate(translator),
+				Field:  fieldPath(verror),
+				Error:  verror.Translate(translator),
+				verror: verror,
 			}
 			fields = append(fields, field)
 		}
@@ -63,3 +88,26 @@ func Check(val interface{}) error {
 
 	return nil
 }
+
+// fieldPath returns the full path to the field in error using the JSON names ie: roles[0] or
+// address.city. The name of the top level struct is not included.
+func fieldPath(verror validator.FieldError) string {
+	ns := verror.Namespace()
+	if i := strings.Index(ns, "."); i != -1 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+// GenerateID generate a unique id for entities.
+func GenerateID() string {
+	return uuid.NewString()
+}
+
+// CheckID validates that the format of an id is valid.
+func CheckID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New("ID is not in its proper form")
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package validate_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rdforte/go-service/business/sys/validate"
+	"github.com/rdforte/go-service/foundation/logger"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type account struct {
+	ID       string    `json:"id" validate:"id"`
+	Password string    `json:"password" validate:"password"`
+	Roles    []string  `json:"roles" validate:"required,dive,role"`
+	Address  address   `json:"address"`
+	Previous []address `json:"previous" validate:"dive"`
+}
+
+func TestCheck(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Validating models against their declared tags")
+	{
+		tl.It("should accept a model satisfying the project specific rules")
+		{
+			acc := account{
+				ID:       validate.GenerateID(),
+				Password: "gophers123",
+				Roles:    []string{"ADMIN", "USER"},
+				Address:  address{City: "Melbourne"},
+			}
+
+			if err := validate.Check(acc); err != nil {
+				tl.Failed("Should be able to validate the model", err)
+			}
+			tl.Success("Should be able to validate the model")
+		}
+
+		tl.It("should report the full path of every invalid field")
+		{
+			acc := account{
+				ID:       "not-an-id",
+				Password: "gophers",
+				Roles:    []string{"USER", "PIRATE"},
+				Previous: []address{{City: "Sydney"}, {}},
+			}
+
+			err := validate.Check(acc)
+
+			var fields validate.FieldErrors
+			if !errors.As(err, &fields) {
+				tl.Failed("Should return field errors", fmt.Errorf("err: %v", err))
+			}
+			tl.Success("Should return field errors")
+
+			got := make(map[string]string)
+			for _, field := range fields {
+				got[field.Field] = field.Error
+			}
+
+			exp := map[string]string{
+				"id":               "id must be a valid ID",
+				"password":         "password must be between 8 and 72 characters and contain at least one letter and one number",
+				"roles[1]":         "roles[1] must be a valid role",
+				"address.city":     "city is a required field",
+				"previous[1].city": "city is a required field",
+			}
+
+			for field, msg := range exp {
+				if got[field] != msg {
+					tl.Failed("Should have the expected field errors", fmt.Errorf("field[%s] exp[%s] got[%s]", field, msg, got[field]))
+				}
+			}
+			if len(got) != len(exp) {
+				tl.Failed("Should have the expected field errors", fmt.Errorf("got: %v", got))
+			}
+			tl.Success("Should have the expected field errors")
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Translating validation errors for the requested locale")
+	{
+		tl.It("should pick the best supported locale from Accept-Language")
+		{
+			tests := map[string]string{
+				"":                          "en",
+				"fr-CA,fr;q=0.9,en;q=0.8":   "fr",
+				"it,de;q=0.7,es;q=0.8":      "es",
+				"it, ja":                    "en",
+				"de-AT":                     "de",
+				"es;q=0,fr;q=0.1":           "fr",
+				"en-GB;q=0.5,de_DE;q=0.6,*": "de",
+			}
+
+			for header, exp := range tests {
+				if got := validate.Locale(header); got != exp {
+					tl.Failed("Should pick the expected locale", fmt.Errorf("header[%s] exp[%s] got[%s]", header, exp, got))
+				}
+			}
+			tl.Success("Should pick the expected locale")
+		}
+
+		tl.It("should translate field errors falling back to english when missing")
+		{
+			acc := account{
+				ID:       validate.GenerateID(),
+				Password: "gophers123",
+				Roles:    []string{"ADMIN"},
+				Address:  address{City: "Berlin"},
+			}
+
+			var fields validate.FieldErrors
+			if err := validate.Check(struct {
+				Account account `json:"account"`
+				Name    string  `json:"name" validate:"required"`
+				Age     int     `json:"age" validate:"gte=18"`
+				Nick    string  `json:"nick" validate:"min=3"`
+				Tags    []int   `json:"tags" validate:"max=1"`
+				Code    string  `json:"code" validate:"alpha"`
+			}{Account: acc, Nick: "go", Tags: []int{1, 2}, Code: "a1"}); !errors.As(err, &fields) {
+				tl.Failed("Should return field errors", fmt.Errorf("err: %v", err))
+			}
+
+			exp := map[string]string{
+				"name": "name ist ein Pflichtfeld",
+				"age":  "age muss 18 oder größer sein",
+				"nick": "nick muss mindestens 3 Zeichen lang sein",
+				"tags": "die Anzahl der Elemente von tags darf höchstens 1 sein",
+				"code": "code can only contain alphabetic characters",
+			}
+
+			if len(fields) != len(exp) {
+				tl.Failed("Should translate the field errors", fmt.Errorf("fields: %v", fields))
+			}
+			for _, field := range fields.Translate("de") {
+				if exp[field.Field] != field.Error {
+					tl.Failed("Should translate the field errors", fmt.Errorf("field[%s] exp[%s] got[%s]", field.Field, exp[field.Field], field.Error))
+				}
+			}
+			tl.Success("Should translate the field errors")
+
+			if msg := validate.Message("es", validate.CodeForbidden, "fallback"); msg != "la acción intentada no está permitida" {
+				tl.Failed("Should translate error codes", fmt.Errorf("got[%s]", msg))
+			}
+			if msg := validate.Message("de", "UNKNOWN_CODE", "fallback"); msg != "fallback" {
+				tl.Failed("Should translate error codes", fmt.Errorf("got[%s]", msg))
+			}
+			tl.Success("Should translate error codes")
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package mid
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rdforte/go-service/foundation/web"
+	"go.uber.org/zap"
+)
+
+// AccessLogConfig configures the access log.
+type AccessLogConfig struct {
+
+	// SkipPaths are request paths of the API which are not logged, such as a route polled by a
+	// monitor. The health checks are served by the debug mux so they never reach the access log.
+	SkipPaths []string
+
+	// SlowThreshold logs requests taking longer than it at warn level. Zero disables it.
+	SlowThreshold time.Duration
+
+	// Fields limits the logged fields to the ones named. Every field is logged when empty.
+	Fields []string
+}
+
+// The set of fields written by the access log.
+const (
+	FieldTraceID    = "traceid"
+	FieldMethod     = "method"
+	FieldPath       = "path"
+	FieldRoute      = "route"
+	FieldStatusCode = "statusCode"
+	FieldBytes      = "bytes"
+	FieldLatency    = "latency"
+	FieldRemoteAddr = "remoteAddr"
+	FieldUserAgent  = "userAgent"
+	FieldSubject    = "subject"
+)
+
+/**
+AccessLog writes a single line for each request once it has completed. The line contains the route
+template, the bytes written, the subject of the authenticated claims, the user agent and the latency
+of the request. It must be the outer most middleware so it sees the final response.
+*/
+func AccessLog(log *zap.SugaredLogger, cfg AccessLogConfig) web.Middleware {
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, path := range cfg.SkipPaths {
+		skip[path] = true
+	}
+
+	fields := make(map[string]bool, len(cfg.Fields))
+	for _, field := range cfg.Fields {
+		fields[field] = true
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached to the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if skip[r.URL.Path] {
+				return handler(ctx, w, r)
+			}
+
+			// If the context is missing this value, request for the service to be shutdown gracefully.
+			v, err := web.GetValues(ctx)
+			if err != nil {
+				return web.NewShutdownError("web value missing from context")
+			}
+
+			// The subject is set by Authenticate further down the chain.
+			var entry accessEntry
+			ctx = context.WithValue(ctx, accessKey, &entry)
+
+			rec := responseRecorder{ResponseWriter: w}
+
+			// Call the next handler.
+			err = handler(ctx, &rec, r.WithContext(ctx))
+
+			latency := time.Since(v.Now)
+
+			kv := []interface{}{
+				FieldTraceID, v.TracedID,
+				FieldMethod, r.Method,
+				FieldPath, r.URL.Path,
+				FieldRoute, v.Route,
+				FieldStatusCode, rec.statusCode(),
+				FieldBytes, rec.bytes,
+				FieldLatency, latency,
+				FieldRemoteAddr, r.RemoteAddr,
+				FieldUserAgent, r.UserAgent(),
+				FieldSubject, entry.subject,
+			}
+
+			if len(fields) > 0 {
+				selected := make([]interface{}, 0, 2*len(fields))
+				for i := 0; i < len(kv); i += 2 {
+					if fields[kv[i].(string)] {
+						selected = append(selected, kv[i], kv[i+1])
+					}
+				}
+				kv = selected
+			}
+
+			if cfg.SlowThreshold > 0 && latency > cfg.SlowThreshold {
+				log.Warnw("slow request", kv...)
+			} else {
+				log.Infow("request", kv...)
+			}
+
+			return err
+		}
+		return h
+	}
+	return m
+}
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// accessKey is how the access log entry is stored/retrieved.
+const accessKey ctxKey = 1
+
+// accessEntry holds the details of the request which are only known further down the chain.
+type accessEntry struct {
+	subject string
+}
+
+// setSubject records the subject of the authenticated claims for the access log.
+func setSubject(ctx context.Context, subject string) {
+	if entry, ok := ctx.Value(accessKey).(*accessEntry); ok {
+		entry.subject = subject
+	}
+}
+
+// responseRecorder records the status code and the number of bytes written to the response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// WriteHeader records the status code before writing it.
+func (rr *responseRecorder) WriteHeader(statusCode int) {
+	if rr.status == 0 {
+		rr.status = statusCode
+	}
+	rr.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the number of bytes written.
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+// Flush sends any buffered data to the client when supported.
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection when supported.
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking not supported")
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// statusCode returns the status code written, no write means an implicit 200.
+func (rr *responseRecorder) statusCode() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+	return rr.status
+}
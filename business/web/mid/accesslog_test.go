@@ -0,0 +1,146 @@
+package mid_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdforte/go-service/business/web/mid"
+	"github.com/rdforte/go-service/foundation/logger"
+	"github.com/rdforte/go-service/foundation/web"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAccessLog(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	// serve handles a request through the access log and returns the lines it wrote.
+	serve := func(cfg mid.AccessLogConfig, path string, handler web.Handler) []map[string]interface{} {
+		var buf bytes.Buffer
+		core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zap.DebugLevel)
+		log := zap.New(core).Sugar()
+
+		app := web.NewApp(make(chan os.Signal, 1), []web.Middleware{mid.AccessLog(log, cfg)})
+		app.Get("/v1/users/{id}", handler)
+		app.Get("/v1/ping", handler)
+
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("User-Agent", "test")
+		app.ServeHTTP(httptest.NewRecorder(), r)
+
+		var lines []map[string]interface{}
+		dec := json.NewDecoder(&buf)
+		for {
+			var line map[string]interface{}
+			if err := dec.Decode(&line); err != nil {
+				if err != io.EOF {
+					tl.Failed("Should decode the log line", err)
+				}
+				return lines
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	respond := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "hello ")
+		_, err := io.WriteString(w, "world")
+		return err
+	}
+
+	tl.Describe("Writing the access log")
+	{
+		tl.It("should write a line with every field for a request")
+		{
+			lines := serve(mid.AccessLogConfig{}, "/v1/users/1", respond)
+			if len(lines) != 1 {
+				tl.Failed("Should write a single line", fmt.Errorf("lines[%d]", len(lines)))
+			}
+			tl.Success("Should write a single line")
+
+			line := lines[0]
+			if line["level"] != "info" || line["msg"] != "request" || line[mid.FieldRoute] != "/v1/users/{id}" ||
+				line[mid.FieldPath] != "/v1/users/1" || line[mid.FieldStatusCode] != float64(http.StatusCreated) ||
+				line[mid.FieldBytes] != float64(len("hello world")) || line[mid.FieldUserAgent] != "test" {
+				tl.Failed("Should log the request", fmt.Errorf("line[%v]", line))
+			}
+			tl.Success("Should log the request")
+
+			for _, field := range []string{mid.FieldTraceID, mid.FieldMethod, mid.FieldLatency, mid.FieldRemoteAddr, mid.FieldSubject} {
+				if _, ok := line[field]; !ok {
+					tl.Failed(fmt.Sprintf("Should log the %s", field), fmt.Errorf("line[%v]", line))
+				}
+				tl.Success(fmt.Sprintf("Should log the %s", field))
+			}
+		}
+
+		tl.It("should only write the selected fields")
+		{
+			lines := serve(mid.AccessLogConfig{Fields: []string{mid.FieldStatusCode, mid.FieldBytes}}, "/v1/users/1", respond)
+
+			var logged []string
+			for field := range lines[0] {
+				if field != "level" && field != "msg" && field != "ts" {
+					logged = append(logged, field)
+				}
+			}
+			if len(logged) != 2 || lines[0][mid.FieldStatusCode] == nil || lines[0][mid.FieldBytes] == nil {
+				tl.Failed("Should log the selected fields", fmt.Errorf("fields%v", logged))
+			}
+			tl.Success("Should log the selected fields")
+		}
+
+		tl.It("should not write a line for skipped paths")
+		{
+			lines := serve(mid.AccessLogConfig{SkipPaths: []string{"/v1/ping"}}, "/v1/ping", respond)
+			if len(lines) != 0 {
+				tl.Failed("Should skip the path", fmt.Errorf("lines[%d]", len(lines)))
+			}
+			tl.Success("Should skip the path")
+		}
+
+		tl.It("should write slow requests at warn level")
+		{
+			slow := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				time.Sleep(5 * time.Millisecond)
+				return respond(ctx, w, r)
+			}
+
+			lines := serve(mid.AccessLogConfig{SlowThreshold: time.Millisecond}, "/v1/users/1", slow)
+			if len(lines) != 1 || lines[0]["level"] != "warn" || lines[0]["msg"] != "slow request" {
+				tl.Failed("Should warn about the slow request", fmt.Errorf("lines%v", lines))
+			}
+			tl.Success("Should warn about the slow request")
+
+			lines = serve(mid.AccessLogConfig{SlowThreshold: time.Hour}, "/v1/users/1", respond)
+			if len(lines) != 1 || lines[0]["level"] != "info" {
+				tl.Failed("Should log a fast request at info level", fmt.Errorf("lines%v", lines))
+			}
+			tl.Success("Should log a fast request at info level")
+		}
+
+		tl.It("should count the bytes of an implicit 200")
+		{
+			implicit := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				_, err := io.WriteString(w, strings.Repeat("x", 100))
+				return err
+			}
+
+			lines := serve(mid.AccessLogConfig{}, "/v1/users/1", implicit)
+			if lines[0][mid.FieldStatusCode] != float64(http.StatusOK) || lines[0][mid.FieldBytes] != float64(100) {
+				tl.Failed("Should count the bytes", fmt.Errorf("line[%v]", lines[0]))
+			}
+			tl.Success("Should count the bytes")
+		}
+	}
+}
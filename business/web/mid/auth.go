@@ -44,6 +44,7 @@ func Authenticate(a *auth.Auth) web.Middleware {
 			}
 
 			ctx = auth.SetClaims(ctx, claims)
+			setSubject(ctx, claims.Subject)
 
 			return handler(ctx, w, r)
 		}
@@ -0,0 +1,275 @@
+package mid
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rdforte/go-service/foundation/web"
+)
+
+// CompressConfig configures the compression of responses.
+type CompressConfig struct {
+
+	// MinSize is the smallest response body in bytes which is compressed. Defaults to 1024.
+	MinSize int
+
+	// ContentTypes are the media types which are compressed. A type ending in /* matches
+	// any subtype. Defaults to JSON responses.
+	ContentTypes []string
+}
+
+// The set of encodings supported by Compress in order of preference. Brotli is not supported
+// since the standard library does not provide an encoder.
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+/**
+Compress compresses the response body with gzip or deflate based on the Accept-Encoding header of the
+request. Responses smaller than the MinSize, with a content type not in the allow list, already encoded,
+without a body (204, 304 and HEAD requests) are sent as is. It must be placed outside of Errors so the
+error responses are compressed as well.
+*/
+func Compress(cfg CompressConfig) web.Middleware {
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = 1024
+	}
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = []string{"application/json", "application/problem+json"}
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached to the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.Method == http.MethodHead {
+				return handler(ctx, w, r)
+			}
+
+			cw := compressWriter{
+				ResponseWriter: w,
+				cfg:            &cfg,
+				encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding")),
+			}
+
+			err := handler(ctx, &cw, r)
+
+			if cerr := cw.close(); cerr != nil && err == nil {
+				err = cerr
+			}
+
+			return err
+		}
+		return h
+	}
+	return m
+}
+
+// compressWriter buffers the response until it knows whether the response should be
+// compressed and then writes it either compressed or as is.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      *CompressConfig
+	encoding string
+
+	status  int
+	buf     []byte
+	started bool
+	encoder io.WriteCloser
+}
+
+// WriteHeader records the status code until the first bytes of the body are written.
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.started || cw.status != 0 {
+		return
+	}
+	cw.status = statusCode
+
+	// Responses without a body are written straight away.
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		cw.start(false)
+	}
+}
+
+// Write buffers the body until the MinSize is reached.
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.started {
+		return cw.write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) < cw.cfg.MinSize {
+		return len(b), nil
+	}
+
+	cw.start(cw.compressible())
+	if _, err := cw.write(cw.buf); err != nil {
+		return 0, err
+	}
+	cw.buf = nil
+
+	return len(b), nil
+}
+
+// Flush writes the buffered body and flushes the encoder and the ResponseWriter.
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		cw.start(cw.compressible() && len(cw.buf) >= cw.cfg.MinSize)
+		cw.write(cw.buf)
+		cw.buf = nil
+	}
+
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close writes any buffered body, which is smaller than the MinSize, as is and finishes
+// the compressed stream.
+func (cw *compressWriter) close() error {
+	if !cw.started {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			return nil
+		}
+		cw.start(false)
+		if _, err := cw.write(cw.buf); err != nil {
+			return err
+		}
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
+
+// start writes the headers and the status code choosing whether the body is compressed.
+func (cw *compressWriter) start(compress bool) {
+	cw.started = true
+
+	h := cw.Header()
+	if cw.eligible() {
+		h.Add("Vary", "Accept-Encoding")
+	}
+
+	if compress {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+
+		// The compressed bytes differ from the bytes a strong ETag was created from.
+		if etag := h.Get("ETag"); etag != "" {
+			h.Set("ETag", web.CodingETag(etag, cw.encoding))
+		}
+
+		switch cw.encoding {
+		case encodingGzip:
+			cw.encoder = gzip.NewWriter(cw.ResponseWriter)
+		case encodingDeflate:
+			// The deflate coding is the zlib format, not a raw deflate stream (RFC 9110 8.4.1.2).
+			cw.encoder = zlib.NewWriter(cw.ResponseWriter)
+		}
+	}
+
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+}
+
+// write writes to the encoder when compressing.
+func (cw *compressWriter) write(b []byte) (int, error) {
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// eligible reports whether the response could be compressed depending on the request, which
+// means caches must vary on the Accept-Encoding header. A 304 carries the Vary of the response
+// it stands in for, which is assumed eligible when it does not carry a content type.
+func (cw *compressWriter) eligible() bool {
+	if cw.status == http.StatusNoContent {
+		return false
+	}
+	if cw.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	if cw.status == http.StatusNotModified && cw.Header().Get("Content-Type") == "" {
+		return true
+	}
+	return allowedType(cw.Header().Get("Content-Type"), cw.cfg.ContentTypes)
+}
+
+// compressible reports whether the response is compressed for this request.
+func (cw *compressWriter) compressible() bool {
+	return cw.encoding != "" && cw.eligible()
+}
+
+// allowedType reports whether the content type is in the allow list.
+func allowedType(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, a := range allowed {
+		if a == mediaType {
+			return true
+		}
+		if strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding returns the supported encoding the client prefers from the Accept-Encoding
+// header. An empty string means the response must not be compressed.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		if len(fields) > 1 && strings.HasPrefix(strings.TrimSpace(fields[1]), "q=") {
+			v, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(fields[1]), "q="), 64)
+			if err != nil {
+				continue
+			}
+			quality = v
+		}
+		qualities[coding] = quality
+	}
+
+	var best string
+	var bestQuality float64
+	for _, coding := range []string{encodingGzip, encodingDeflate} {
+		quality, ok := qualities[coding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+		if ok && quality > bestQuality {
+			best, bestQuality = coding, quality
+		}
+	}
+	return best
+}
@@ -0,0 +1,240 @@
+package mid_test
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rdforte/go-service/business/web/mid"
+	"github.com/rdforte/go-service/foundation/logger"
+	"github.com/rdforte/go-service/foundation/web"
+)
+
+func TestCompress(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	large := `{"data":"` + strings.Repeat("x", 2048) + `"}`
+	small := `{"data":"x"}`
+
+	// serve runs the handler through Compress for a request accepting the encodings.
+	serve := func(method string, acceptEncoding string, handler web.Handler) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", nil)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		if err := mid.Compress(mid.CompressConfig{})(handler)(context.Background(), w, r); err != nil {
+			tl.Failed("Should handle the request", err)
+		}
+		return w
+	}
+
+	// respond writes the body with the content type and any extra headers.
+	respond := func(status int, contentType string, body string, headers ...string) web.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Header().Set("Content-Type", contentType)
+			for i := 0; i+1 < len(headers); i += 2 {
+				w.Header().Set(headers[i], headers[i+1])
+			}
+			w.WriteHeader(status)
+			if body == "" {
+				return nil
+			}
+			_, err := io.WriteString(w, body)
+			return err
+		}
+	}
+
+	// decode returns the body of the response, decompressed when it is encoded.
+	decode := func(w *httptest.ResponseRecorder) (string, error) {
+		var rd io.Reader = w.Body
+		switch w.Header().Get("Content-Encoding") {
+		case "gzip":
+			zr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				return "", err
+			}
+			rd = zr
+		case "deflate":
+			zr, err := zlib.NewReader(w.Body)
+			if err != nil {
+				return "", err
+			}
+			rd = zr
+		}
+		b, err := io.ReadAll(rd)
+		return string(b), err
+	}
+
+	tl.Describe("Compressing responses")
+	{
+		tl.It("should only compress responses of at least the MinSize")
+		{
+			w := serve(http.MethodGet, "gzip", respond(http.StatusOK, "application/json", large))
+			body, err := decode(w)
+			if w.Header().Get("Content-Encoding") != "gzip" || err != nil || body != large {
+				tl.Failed("Should compress a large response", fmt.Errorf("encoding[%s] err[%v]", w.Header().Get("Content-Encoding"), err))
+			}
+			tl.Success("Should compress a large response")
+
+			w = serve(http.MethodGet, "gzip", respond(http.StatusOK, "application/json", small))
+			if w.Header().Get("Content-Encoding") != "" || w.Body.String() != small {
+				tl.Failed("Should send a small response as is", fmt.Errorf("encoding[%s] body[%s]", w.Header().Get("Content-Encoding"), w.Body))
+			}
+			tl.Success("Should send a small response as is")
+		}
+
+		tl.It("should send responses without a body as is")
+		{
+			tests := []struct {
+				status      int
+				contentType string
+				vary        string
+			}{
+				{http.StatusNoContent, "application/json", ""},
+				{http.StatusNotModified, "", "Accept-Encoding"},
+				{http.StatusNotModified, "application/json", "Accept-Encoding"},
+				{http.StatusNotModified, "text/plain", ""},
+			}
+
+			for _, tt := range tests {
+				w := serve(http.MethodGet, "gzip", respond(tt.status, tt.contentType, ""))
+				if w.Code != tt.status || w.Header().Get("Content-Encoding") != "" || w.Header().Get("Vary") != tt.vary || w.Body.Len() != 0 {
+					tl.Failed(fmt.Sprintf("Should send a %d %q as is", tt.status, tt.contentType), fmt.Errorf("encoding[%s] vary[%s]", w.Header().Get("Content-Encoding"), w.Header().Get("Vary")))
+				}
+				tl.Success(fmt.Sprintf("Should send a %d %q as is", tt.status, tt.contentType))
+			}
+
+			w := serve(http.MethodHead, "gzip", respond(http.StatusOK, "application/json", large))
+			if w.Header().Get("Content-Encoding") != "" {
+				tl.Failed("Should send a HEAD response as is", fmt.Errorf("encoding[%s]", w.Header().Get("Content-Encoding")))
+			}
+			tl.Success("Should send a HEAD response as is")
+		}
+
+		tl.It("should not compress a response which is already encoded")
+		{
+			w := serve(http.MethodGet, "gzip", respond(http.StatusOK, "application/json", large, "Content-Encoding", "br"))
+			if w.Header().Get("Content-Encoding") != "br" || w.Body.String() != large || w.Header().Get("Vary") != "" {
+				tl.Failed("Should send the encoded response as is", fmt.Errorf("encoding[%s] vary[%s]", w.Header().Get("Content-Encoding"), w.Header().Get("Vary")))
+			}
+			tl.Success("Should send the encoded response as is")
+		}
+
+		tl.It("should vary on Accept-Encoding whenever the response could be compressed")
+		{
+			tests := []struct {
+				name           string
+				acceptEncoding string
+				contentType    string
+				vary           string
+			}{
+				{"compressed", "gzip", "application/json", "Accept-Encoding"},
+				{"not accepted", "", "application/json", "Accept-Encoding"},
+				{"not allowed", "gzip", "text/plain", ""},
+			}
+
+			for _, tt := range tests {
+				w := serve(http.MethodGet, tt.acceptEncoding, respond(http.StatusOK, tt.contentType, large))
+				if w.Header().Get("Vary") != tt.vary {
+					tl.Failed(fmt.Sprintf("Should vary %q when %s", tt.vary, tt.name), fmt.Errorf("vary[%s]", w.Header().Get("Vary")))
+				}
+				tl.Success(fmt.Sprintf("Should vary %q when %s", tt.vary, tt.name))
+			}
+		}
+
+		tl.It("should encode deflate responses in the zlib format")
+		{
+			w := serve(http.MethodGet, "deflate", respond(http.StatusOK, "application/json", large))
+			body, err := decode(w)
+			if w.Header().Get("Content-Encoding") != "deflate" || err != nil || body != large {
+				tl.Failed("Should decode the response with zlib", fmt.Errorf("encoding[%s] err[%v]", w.Header().Get("Content-Encoding"), err))
+			}
+			tl.Success("Should decode the response with zlib")
+		}
+
+		tl.It("should write the buffered body when flushed")
+		{
+			var flushed string
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{"data":"`)
+				if err := http.NewResponseController(w).Flush(); err != nil {
+					return err
+				}
+				flushed = w.(interface{ Unwrap() http.ResponseWriter }).Unwrap().(*httptest.ResponseRecorder).Body.String()
+				_, err := io.WriteString(w, strings.Repeat("x", 2048)+`"}`)
+				return err
+			}
+
+			w := serve(http.MethodGet, "gzip", handler)
+			body, err := decode(w)
+			if !w.Flushed || flushed != `{"data":"` || err != nil || body != large {
+				tl.Failed("Should flush the response", fmt.Errorf("flushed[%v] before[%s] err[%v]", w.Flushed, flushed, err))
+			}
+			tl.Success("Should flush the response")
+		}
+
+		tl.It("should keep the version of a compressed response strong and usable in If-Match")
+		{
+			for _, encoding := range []string{"gzip", "deflate"} {
+				w := serve(http.MethodGet, encoding, respond(http.StatusOK, "application/json", large, "ETag", `"3"`))
+				if etag := w.Header().Get("ETag"); etag != `"3-`+encoding+`"` {
+					tl.Failed(fmt.Sprintf("Should set a strong %s ETag", encoding), fmt.Errorf("etag[%s]", etag))
+				}
+				tl.Success(fmt.Sprintf("Should set a strong %s ETag", encoding))
+
+				r := httptest.NewRequest(http.MethodPut, "/", nil)
+				r.Header.Set("If-Match", w.Header().Get("ETag"))
+				if versions := web.IfMatch(r); len(versions) != 1 || versions[0] != "3" {
+					tl.Failed(fmt.Sprintf("Should return the version of the %s ETag", encoding), fmt.Errorf("etag[%s] versions%v", w.Header().Get("ETag"), versions))
+				}
+				tl.Success(fmt.Sprintf("Should return the version of the %s ETag", encoding))
+			}
+
+			w := serve(http.MethodGet, "gzip", respond(http.StatusOK, "application/json", large, "ETag", `W/"3"`))
+			if etag := w.Header().Get("ETag"); etag != `W/"3"` {
+				tl.Failed("Should keep a weak ETag as is", fmt.Errorf("etag[%s]", etag))
+			}
+			tl.Success("Should keep a weak ETag as is")
+		}
+	}
+
+	tl.Describe("Negotiating the encoding from the Accept-Encoding header")
+	{
+		tests := []struct {
+			acceptEncoding string
+			encoding       string
+		}{
+			{"gzip", "gzip"},
+			{"deflate", "deflate"},
+			{"gzip, deflate", "gzip"},
+			{"deflate, gzip;q=0.5", "deflate"},
+			{"GZIP;q=0.2, deflate;q=0.1", "gzip"},
+			{"gzip;q=0, deflate;q=0", ""},
+			{"*", "gzip"},
+			{"*;q=0.5, gzip;q=0", "deflate"},
+			{"identity;q=0", ""},
+			{"identity;q=0, *", "gzip"},
+			{"br", ""},
+			{"gzip;q=invalid", ""},
+		}
+
+		for _, tt := range tests {
+			tl.It(fmt.Sprintf("should choose %q for %q", tt.encoding, tt.acceptEncoding))
+			{
+				w := serve(http.MethodGet, tt.acceptEncoding, respond(http.StatusOK, "application/json", large))
+				if w.Header().Get("Content-Encoding") != tt.encoding {
+					tl.Failed("Should choose the preferred encoding", fmt.Errorf("encoding[%s]", w.Header().Get("Content-Encoding")))
+				}
+				tl.Success("Should choose the preferred encoding")
+			}
+		}
+	}
+}
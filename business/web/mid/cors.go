@@ -0,0 +1,182 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rdforte/go-service/foundation/web"
+)
+
+// CORSPolicy describes which cross origin requests are allowed.
+type CORSPolicy struct {
+
+	// AllowedOrigins are exact origins (https://app.example.com), wildcard subdomains
+	// (https://*.example.com) or * to allow any origin.
+	AllowedOrigins []string
+
+	// AllowedMethods default to GET, POST, PUT, PATCH and DELETE.
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers the browser may send.
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers the browser may read.
+	ExposedHeaders []string
+
+	// AllowCredentials allows cookies to be sent. The origin is always echoed back since
+	// browsers reject a wildcard origin for requests with credentials. It can not be combined
+	// with allowing any origin, which would let any site make authenticated requests.
+	AllowCredentials bool
+
+	// MaxAge is how long the browser may cache the result of a preflight request.
+	MaxAge time.Duration
+}
+
+/**
+CORS responds to cross origin requests using the policy of the route template which matched the
+request, falling back to the default policy. Preflight requests are responded to without calling the
+handler. It must be app middleware so it runs for the OPTIONS routes registered by web.App. An error is
+returned when a policy allows credentials from any origin.
+*/
+func CORS(policy CORSPolicy, routes map[string]CORSPolicy) (web.Middleware, error) {
+	def, err := newCORS(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string]*cors, len(routes))
+	for route, p := range routes {
+		c, err := newCORS(p)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", route, err)
+		}
+		policies[route] = c
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached to the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			c := def
+			if v, err := web.GetValues(ctx); err == nil {
+				if p, ok := policies[v.Route]; ok {
+					c = p
+				}
+			}
+
+			// The response depends on the origin unless every origin gets the same response.
+			if !c.anyOrigin || c.policy.AllowCredentials {
+				w.Header().Add("Vary", "Origin")
+			}
+
+			origin := r.Header.Get("Origin")
+			if origin == "" || !c.allowOrigin(origin) {
+				return handler(ctx, w, r)
+			}
+
+			h := w.Header()
+			if c.anyOrigin && !c.policy.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if c.policy.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			// A preflight request asks which method and headers the actual request may use.
+			reqMethod := r.Header.Get("Access-Control-Request-Method")
+			if r.Method != http.MethodOptions || reqMethod == "" {
+				if len(c.policy.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(c.policy.ExposedHeaders, ", "))
+				}
+				return handler(ctx, w, r)
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+
+			if c.methods[strings.ToUpper(reqMethod)] {
+				h.Set("Access-Control-Allow-Methods", strings.Join(c.policy.AllowedMethods, ", "))
+				if len(c.policy.AllowedHeaders) > 0 {
+					h.Set("Access-Control-Allow-Headers", strings.Join(c.policy.AllowedHeaders, ", "))
+				}
+				if c.policy.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.policy.MaxAge.Seconds())))
+				}
+			}
+
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+		return h
+	}
+	return m, nil
+}
+
+// cors is a CORSPolicy prepared for matching requests.
+type cors struct {
+	policy    CORSPolicy
+	anyOrigin bool
+	origins   map[string]bool
+	wildcards []string
+	methods   map[string]bool
+}
+
+// newCORS prepares the policy applying the defaults.
+func newCORS(policy CORSPolicy) (*cors, error) {
+	if len(policy.AllowedMethods) == 0 {
+		policy.AllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+
+	c := cors{
+		policy:  policy,
+		origins: make(map[string]bool),
+		methods: make(map[string]bool),
+	}
+
+	for _, origin := range policy.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
+			c.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			c.wildcards = append(c.wildcards, origin)
+		default:
+			c.origins[origin] = true
+		}
+	}
+
+	for _, method := range policy.AllowedMethods {
+		c.methods[strings.ToUpper(method)] = true
+	}
+
+	if c.anyOrigin && policy.AllowCredentials {
+		return nil, errors.New("credentials can not be allowed from any origin")
+	}
+
+	return &c, nil
+}
+
+// allowOrigin reports whether the origin is allowed. A wildcard matches subdomains
+// at any depth but not the domain itself.
+func (c *cors) allowOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	if c.anyOrigin || c.origins[origin] {
+		return true
+	}
+
+	for _, wildcard := range c.wildcards {
+		scheme, domain, _ := strings.Cut(wildcard, "://*")
+		if strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, domain) &&
+			len(origin) > len(scheme)+len("://")+len(domain) {
+			return true
+		}
+	}
+	return false
+}
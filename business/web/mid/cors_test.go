@@ -0,0 +1,157 @@
+package mid_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rdforte/go-service/business/web/mid"
+	"github.com/rdforte/go-service/foundation/logger"
+	"github.com/rdforte/go-service/foundation/web"
+)
+
+func TestCORS(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	policy := mid.CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"ETag"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}
+	routes := map[string]mid.CORSPolicy{
+		"/v1/public/{id}": {AllowedOrigins: []string{"*"}},
+	}
+
+	cors, err := mid.CORS(policy, routes)
+	if err != nil {
+		t.Fatalf("constructing cors: %v", err)
+	}
+
+	app := web.NewApp(make(chan os.Signal, 1), []web.Middleware{cors})
+	ok := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.RespondOk(ctx, w)
+	}
+	app.Get("/v1/users", ok)
+	app.Post("/v1/users", ok)
+	app.Get("/v1/public/{id}", ok)
+
+	serve := func(method string, path string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+
+	tl.Describe("Responding to cross origin requests")
+	{
+		tl.It("should only allow the origins of the policy")
+		{
+			tests := []struct {
+				origin  string
+				allowed bool
+			}{
+				{"https://app.example.com", true},
+				{"https://APP.example.com", true},
+				{"https://other.example.com", false},
+				{"https://a.example.org", true},
+				{"https://a.b.example.org", true},
+				{"https://example.org", false},
+				{"https://example.org.evil.com", false},
+				{"https://evilexample.org", false},
+				{"http://a.example.org", false},
+			}
+
+			for _, tt := range tests {
+				w := serve(http.MethodGet, "/v1/users", map[string]string{"Origin": tt.origin})
+				got := w.Header().Get("Access-Control-Allow-Origin")
+				if (got != "") != tt.allowed || w.Code != http.StatusOK {
+					tl.Failed(fmt.Sprintf("Should allow %s: %v", tt.origin, tt.allowed), fmt.Errorf("status[%d] allow-origin[%s]", w.Code, got))
+				}
+				tl.Success(fmt.Sprintf("Should allow %s: %v", tt.origin, tt.allowed))
+			}
+		}
+
+		tl.It("should echo the origin when credentials are allowed")
+		{
+			w := serve(http.MethodGet, "/v1/users", map[string]string{"Origin": "https://a.example.org"})
+			h := w.Header()
+			if h.Get("Access-Control-Allow-Origin") != "https://a.example.org" || h.Get("Access-Control-Allow-Credentials") != "true" ||
+				h.Get("Access-Control-Expose-Headers") != "ETag" || h.Get("Vary") != "Origin" {
+				tl.Failed("Should echo the origin", fmt.Errorf("header[%v]", h))
+			}
+			tl.Success("Should echo the origin")
+		}
+
+		tl.It("should respond to a preflight request without calling the handler")
+		{
+			w := serve(http.MethodOptions, "/v1/users", map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "Authorization",
+			})
+			h := w.Header()
+			if w.Code != http.StatusNoContent || h.Get("Access-Control-Allow-Methods") != "GET, POST" ||
+				h.Get("Access-Control-Allow-Headers") != "Authorization, Content-Type" || h.Get("Access-Control-Max-Age") != "3600" {
+				tl.Failed("Should allow the method and headers", fmt.Errorf("status[%d] header[%v]", w.Code, h))
+			}
+			tl.Success("Should allow the method and headers")
+		}
+
+		tl.It("should not allow a preflight request for a method outside the policy")
+		{
+			w := serve(http.MethodOptions, "/v1/users", map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": http.MethodDelete,
+			})
+			h := w.Header()
+			if w.Code != http.StatusNoContent || h.Get("Access-Control-Allow-Methods") != "" || h.Get("Access-Control-Allow-Headers") != "" {
+				tl.Failed("Should not allow the method", fmt.Errorf("status[%d] header[%v]", w.Code, h))
+			}
+			tl.Success("Should not allow the method")
+		}
+
+		tl.It("should use the policy of the route which matched the request")
+		{
+			w := serve(http.MethodGet, "/v1/public/1", map[string]string{"Origin": "https://anywhere.com"})
+			h := w.Header()
+			if h.Get("Access-Control-Allow-Origin") != "*" || h.Get("Access-Control-Allow-Credentials") != "" || h.Get("Vary") != "" {
+				tl.Failed("Should allow any origin", fmt.Errorf("header[%v]", h))
+			}
+			tl.Success("Should allow any origin")
+
+			w = serve(http.MethodGet, "/v1/users", map[string]string{"Origin": "https://anywhere.com"})
+			if h := w.Header().Get("Access-Control-Allow-Origin"); h != "" {
+				tl.Failed("Should use the default policy for other routes", fmt.Errorf("allow-origin[%s]", h))
+			}
+			tl.Success("Should use the default policy for other routes")
+		}
+	}
+
+	tl.Describe("Constructing a policy")
+	{
+		tl.It("should reject allowing credentials from any origin")
+		{
+			wildcard := mid.CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+
+			if _, err := mid.CORS(wildcard, nil); err == nil {
+				tl.Failed("Should reject the default policy", fmt.Errorf("err[%v]", err))
+			}
+			tl.Success("Should reject the default policy")
+
+			if _, err := mid.CORS(mid.CORSPolicy{}, map[string]mid.CORSPolicy{"/v1/public/{id}": wildcard}); err == nil {
+				tl.Failed("Should reject the route policy", fmt.Errorf("err[%v]", err))
+			}
+			tl.Success("Should reject the route policy")
+		}
+	}
+}
@@ -2,7 +2,9 @@ package mid
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/rdforte/go-service/business/sys/validate"
 	"github.com/rdforte/go-service/foundation/web"
@@ -12,8 +14,19 @@ import (
 /**
 Errors handles errors coming out of the call chain. It detects normal application errors which
 are used to respond to the client in a uniform way. Unexpected errors (status >= 500) are logged.
+
+Shutdown and integrity errors are returned once the response is written so the App can apply its
+shutdown policy.
+
+Requests for any of the provided problemVersions (the first segment of the path ie: "v2") are
+responded to with an RFC 7807 application/problem+json body. All other versions keep the legacy
+ErrorResponse shape so existing clients are not broken.
 */
-func Errors(log *zap.SugaredLogger) web.Middleware {
+func Errors(log *zap.SugaredLogger, problemVersions ...string) web.Middleware {
+	problems := make(map[string]bool, len(problemVersions))
+	for _, version := range problemVersions {
+		problems[version] = true
+	}
 
 	// This the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
@@ -33,40 +46,27 @@ func Errors(log *zap.SugaredLogger) web.Middleware {
 				// Log the error.
 				log.Errorw("ERROR", "traceid", v.TracedID, "ERROR", err)
 
-				// Build out the error response.
-				var er validate.ErrorResponse
-				var status int
-
-				switch act := validate.Cause(err).(type) {
-				case validate.FieldErrors:
-					er = validate.ErrorResponse{
-						Error:  "data validation error",
-						Fields: act.Error(),
-					}
-					status = http.StatusBadRequest
-
-				case *validate.RequestError:
-					er = validate.ErrorResponse{
-						Error: act.Error(),
-					}
-					status = act.Status
-
-				default:
-					// default is a non trusted error so return status 500.
-					er = validate.ErrorResponse{
-						Error: http.StatusText(http.StatusInternalServerError),
-					}
-					status = http.StatusInternalServerError
+				// Build out the error response in the format expected by the version of the API
+				// using the language the client asked for.
+				locale := validate.Locale(r.Header.Get("Accept-Language"))
+				info := translate(err, locale)
+				w.Header().Set("Content-Language", locale)
+
+				var er interface{} = legacyResponse(info)
+				if problems[apiVersion(r)] {
+					er = problemResponse(info, v.TracedID)
+					w.Header().Set("Content-Type", validate.ProblemContentType)
 				}
 
 				// Respond with the error back to the client.
-				if err := web.Respond(ctx, w, er, status); err != nil {
+				if err := web.Respond(ctx, w, er, info.status); err != nil {
 					return err
 				}
 
 				// If we receive the shutdown err then we need to return it back to the base handler
-				// to shutdown the service.
-				if ok := web.IsShutdown(err); ok {
+				// to shutdown the service. Integrity errors are returned too so the App counts them
+				// towards its shutdown policy.
+				if web.IsShutdown(err) || web.IsIntegrity(err) {
 					return err
 				}
 			}
@@ -78,3 +78,106 @@ func Errors(log *zap.SugaredLogger) web.Middleware {
 	}
 	return m
 }
+
+// errorInfo is the result of translating an error coming out of the call chain.
+type errorInfo struct {
+	status int
+	code   string
+	detail string
+	fields validate.FieldErrors
+}
+
+// translate centrally maps an error to the status, code and details to respond with. Only
+// trusted errors have their details exposed to the client. Messages are translated into the
+// locale when a translation is available.
+func translate(err error, locale string) errorInfo {
+	switch act := validate.Cause(err).(type) {
+	case validate.FieldErrors:
+		return errorInfo{
+			status: http.StatusBadRequest,
+			code:   validate.CodeValidation,
+			detail: validate.Message(locale, validate.CodeValidation, "data validation error"),
+			fields: act.Translate(locale),
+		}
+
+	case *validate.RequestError:
+		info := errorInfo{
+			status: act.Status,
+			code:   act.Code(),
+			detail: act.Error(),
+		}
+		var coded *validate.Error
+		if errors.As(act.Err, &coded) {
+			info.detail = validate.Message(locale, coded.Code, info.detail)
+		}
+		if fields, ok := act.Fields.(validate.FieldErrors); ok {
+			info.fields = fields.Translate(locale)
+		}
+		return info
+
+	case *web.Error:
+		// Errors detected by the framework are trusted and converted to a RequestError.
+		reqErr := validate.RequestError{
+			Err:    act.Err,
+			Status: act.Status,
+		}
+		if act.Field != "" {
+			reqErr.Fields = validate.FieldErrors{{Field: act.Field, Error: act.Err.Error()}}
+		}
+		return translate(&reqErr, locale)
+
+	case *validate.Error:
+		// An error code without a registered status is treated as non trusted.
+		if status, ok := validate.StatusOf(act.Code); ok {
+			return errorInfo{
+				status: status,
+				code:   act.Code,
+				detail: validate.Message(locale, act.Code, act.Error()),
+			}
+		}
+	}
+
+	// default is a non trusted error so return status 500.
+	return errorInfo{
+		status: http.StatusInternalServerError,
+		code:   validate.CodeInternal,
+		detail: validate.Message(locale, validate.CodeInternal, http.StatusText(http.StatusInternalServerError)),
+	}
+}
+
+// legacyResponse builds the v1 ErrorResponse for the translated error.
+func legacyResponse(info errorInfo) validate.ErrorResponse {
+	er := validate.ErrorResponse{
+		Error: info.detail,
+		Code:  info.code,
+	}
+	if len(info.fields) > 0 {
+		er.Fields = info.fields.JSON()
+	}
+	return er
+}
+
+// problemResponse builds the RFC 7807 ProblemDetail for the translated error. The trace id is used
+// as the instance so a client can quote it back to us when reporting the problem.
+func problemResponse(info errorInfo, traceID string) validate.ProblemDetail {
+	pd := validate.ProblemDetail{
+		Type:     "about:blank",
+		Title:    http.StatusText(info.status),
+		Status:   info.status,
+		Instance: traceID,
+		Code:     info.code,
+		Errors:   info.fields,
+	}
+
+	// The details of a non trusted error are not exposed.
+	if info.code != validate.CodeInternal {
+		pd.Detail = info.detail
+	}
+
+	return pd
+}
+
+// apiVersion returns the version segment of the request path ie: /v1/user returns v1.
+func apiVersion(r *http.Request) string {
+	return strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+}
@@ -0,0 +1,224 @@
+package mid_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rdforte/go-service/business/core/idempotency"
+	"github.com/rdforte/go-service/business/core/user"
+	"github.com/rdforte/go-service/business/sys/paging"
+	"github.com/rdforte/go-service/business/sys/validate"
+	"github.com/rdforte/go-service/business/web/mid"
+	"github.com/rdforte/go-service/foundation/logger"
+	"github.com/rdforte/go-service/foundation/web"
+	"go.uber.org/zap"
+)
+
+func TestErrorsShutdownPolicy(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	log := zap.NewNop().Sugar()
+
+	tl.Describe("Handing errors to the shutdown policy of the App")
+	{
+		tl.It("should respond to integrity errors and count them towards the threshold")
+		{
+			shutdown := make(chan os.Signal, 1)
+			app := web.NewApp(shutdown, []web.Middleware{mid.Errors(log)}, web.WithShutdownPolicy(web.ShutdownPolicy{
+				Threshold: 2,
+				Window:    time.Minute,
+			}))
+			app.Get("/corrupt", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return fmt.Errorf("querying: %w", web.NewIntegrityError(errors.New("data corrupted")))
+			})
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/corrupt", nil))
+			if w.Code != http.StatusInternalServerError || len(shutdown) != 0 {
+				tl.Failed("Should respond 500 without a shutdown", fmt.Errorf("status[%d] signals[%d]", w.Code, len(shutdown)))
+			}
+			tl.Success("Should respond 500 without a shutdown")
+
+			app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/corrupt", nil))
+			if len(shutdown) != 1 {
+				tl.Failed("Should signal a shutdown at the threshold", fmt.Errorf("signals[%d]", len(shutdown)))
+			}
+			tl.Success("Should signal a shutdown at the threshold")
+		}
+
+		tl.It("should handle every other error")
+		{
+			shutdown := make(chan os.Signal, 1)
+			var reported int
+			app := web.NewApp(shutdown, []web.Middleware{mid.Errors(log)}, web.WithShutdownPolicy(web.ShutdownPolicy{
+				Threshold: 1,
+				OnError: func(ctx context.Context, err error) {
+					reported++
+				},
+			}))
+			app.Get("/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return errors.New("failed")
+			})
+			app.Get("/cancel", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.NewIntegrityError(fmt.Errorf("querying: %w", context.Canceled))
+			})
+
+			for _, path := range []string{"/fail", "/cancel"} {
+				app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			}
+			if reported != 0 || len(shutdown) != 0 {
+				tl.Failed("Should not pass the errors on", fmt.Errorf("reported[%d] signals[%d]", reported, len(shutdown)))
+			}
+			tl.Success("Should not pass the errors on")
+		}
+	}
+}
+
+func TestErrorsProblemDetails(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	var fields validate.FieldErrors
+	if err := validate.Check(struct {
+		Name string `json:"name" validate:"required"`
+	}{}); !errors.As(err, &fields) {
+		t.Fatalf("validating: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail string
+		fields []string
+	}{
+		{"web error", web.NewError(errors.New("unable to decode payload"), http.StatusBadRequest), http.StatusBadRequest, "BAD_REQUEST", "unable to decode payload", nil},
+		{"web field error", &web.Error{Err: errors.New("age must be of type int"), Status: http.StatusBadRequest, Field: "age"}, http.StatusBadRequest, "BAD_REQUEST", "age must be of type int", []string{"age"}},
+		{"field errors", fmt.Errorf("validating: %w", fields), http.StatusBadRequest, validate.CodeValidation, "data validation error", []string{"name"}},
+		{"request error", validate.NewRequestError(validate.ErrForbidden, http.StatusForbidden), http.StatusForbidden, validate.CodeForbidden, "attempted action is not allowed", nil},
+		{"coded error", fmt.Errorf("parsing: %w", validate.ErrInvalidID), http.StatusBadRequest, validate.CodeInvalidID, "ID is not in its proper form", nil},
+		{"untrusted error", errors.New("pq: connection refused"), http.StatusInternalServerError, validate.CodeInternal, "", nil},
+	}
+
+	tl.Describe("Responding to errors with problem details")
+	{
+		for _, tt := range tests {
+			tl.It(fmt.Sprintf("should respond to a %s", tt.name))
+			{
+				app := web.NewApp(make(chan os.Signal, 1), []web.Middleware{mid.Errors(zap.NewNop().Sugar(), "v2")})
+				app.Get("/v2/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return tt.err
+				})
+
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/fail", nil))
+
+				if ct := w.Header().Get("Content-Type"); w.Code != tt.status || ct != validate.ProblemContentType {
+					tl.Failed("Should respond with problem+json", fmt.Errorf("status[%d] content-type[%s]", w.Code, ct))
+				}
+				tl.Success("Should respond with problem+json")
+
+				var pd validate.ProblemDetail
+				if err := json.NewDecoder(w.Body).Decode(&pd); err != nil {
+					tl.Failed("Should decode the problem", err)
+				}
+				if pd.Type != "about:blank" || pd.Title != http.StatusText(tt.status) || pd.Status != tt.status ||
+					pd.Detail != tt.detail || pd.Code != tt.code || pd.Instance == "" {
+					tl.Failed("Should describe the problem", fmt.Errorf("problem[%+v]", pd))
+				}
+				tl.Success("Should describe the problem")
+
+				var got []string
+				for _, field := range pd.Errors {
+					got = append(got, field.Field)
+				}
+				if fmt.Sprint(got) != fmt.Sprint(tt.fields) {
+					tl.Failed("Should list the fields in error", fmt.Errorf("exp%v got%v", tt.fields, got))
+				}
+				tl.Success("Should list the fields in error")
+			}
+		}
+	}
+}
+
+func TestErrorsVersions(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	app := web.NewApp(make(chan os.Signal, 1), []web.Middleware{mid.Errors(zap.NewNop().Sugar(), "v2")})
+	for _, version := range []string{"v1", "v2", "v3"} {
+		app.Get("/"+version+"/users/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return fmt.Errorf("querying: %w", user.ErrNotFound)
+		})
+	}
+
+	tl.Describe("Selecting the error response by the version of the API")
+	{
+		tl.It("should respond to v2 with problem+json")
+		{
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/users/1", nil))
+
+			var pd validate.ProblemDetail
+			if err := json.NewDecoder(w.Body).Decode(&pd); err != nil || w.Header().Get("Content-Type") != validate.ProblemContentType ||
+				pd.Status != http.StatusNotFound || pd.Code != user.CodeNotFound || pd.Detail != "user not found" {
+				tl.Failed("Should respond with problem+json", fmt.Errorf("content-type[%s] problem[%+v] err[%v]", w.Header().Get("Content-Type"), pd, err))
+			}
+			tl.Success("Should respond with problem+json")
+		}
+
+		tl.It("should keep the legacy shape for every other version")
+		{
+			for _, path := range []string{"/v1/users/1", "/v3/users/1"} {
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+				var er map[string]interface{}
+				if err := json.NewDecoder(w.Body).Decode(&er); err != nil || w.Code != http.StatusNotFound ||
+					w.Header().Get("Content-Type") != "application/json" || len(er) != 2 ||
+					er["error"] != "user not found" || er["code"] != user.CodeNotFound {
+					tl.Failed(fmt.Sprintf("Should respond to %s with the legacy shape", path), fmt.Errorf("status[%d] content-type[%s] body[%v] err[%v]", w.Code, w.Header().Get("Content-Type"), er, err))
+				}
+				tl.Success(fmt.Sprintf("Should respond to %s with the legacy shape", path))
+			}
+		}
+	}
+
+	tl.Describe("Responding with the status registered by the package owning the code")
+	{
+		tests := []struct {
+			err    error
+			status int
+		}{
+			{user.ErrNotFound, http.StatusNotFound},
+			{user.ErrAuthenticationFailure, http.StatusUnauthorized},
+			{user.ErrPreconditionFailed, http.StatusPreconditionFailed},
+			{paging.ErrInvalidCursor, http.StatusBadRequest},
+			{idempotency.ErrKeyReused, http.StatusUnprocessableEntity},
+			{idempotency.ErrInFlight, http.StatusConflict},
+		}
+
+		for _, tt := range tests {
+			tl.It(fmt.Sprintf("should respond %d to %q", tt.status, tt.err))
+			{
+				app := web.NewApp(make(chan os.Signal, 1), []web.Middleware{mid.Errors(zap.NewNop().Sugar())})
+				app.Get("/v1/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return tt.err
+				})
+
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/fail", nil))
+				if w.Code != tt.status {
+					tl.Failed("Should respond with the registered status", fmt.Errorf("status[%d]", w.Code))
+				}
+				tl.Success("Should respond with the registered status")
+			}
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package mid
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rdforte/go-service/business/core/idempotency"
+	"github.com/rdforte/go-service/business/sys/auth"
+	"github.com/rdforte/go-service/business/sys/validate"
+	"github.com/rdforte/go-service/foundation/web"
+	"go.uber.org/zap"
+)
+
+// IdempotencyKeyHeader is the header carrying the key the client chose for the request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLen is the longest idempotency key which is accepted.
+const maxIdempotencyKeyLen = 255
+
+// replayHeaders are the response headers stored so a replay is the same response. Headers set
+// by other middleware, such as the request id, are set again when replaying. Credentials, such
+// as Set-Cookie, are never stored.
+var replayHeaders = []string{"Content-Type", "Location", "ETag", "Last-Modified"}
+
+// Idempotent returns the middleware replaying the responses of a route. Routes issuing credentials
+// provide reissue to issue them again when a response is replayed, since they are never stored. It
+// sets the credentials on the response without writing it. Routes issuing no credentials pass nil.
+type Idempotent func(reissue web.Handler) web.Middleware
+
+// IdempotencyConfig configures the replaying of requests made with an Idempotency-Key.
+type IdempotencyConfig struct {
+
+	// TTL is how long the response of a request is replayed. Defaults to 24 hours.
+	TTL time.Duration
+
+	// Secret keys the HMAC identifying requests and anonymous clients, so neither the body, which
+	// may carry a password, nor the address of a client can be recovered from the stored keys.
+	// Every instance of the service must share it. A random secret is used when empty, which
+	// means stored keys are no longer recognised after a restart.
+	Secret []byte
+}
+
+// IdempotencyStore stores the responses replayed by Idempotency. It is satisfied by idempotency.Core.
+type IdempotencyStore interface {
+	Begin(ctx context.Context, nr idempotency.NewRecord, ttl time.Duration, now time.Time) (idempotency.Record, bool, error)
+	Complete(ctx context.Context, key string, subject string, resp idempotency.Response) error
+	Release(ctx context.Context, key string, subject string) error
+}
+
+/**
+Idempotency replays the stored response of a request made with the same Idempotency-Key, so a client
+can safely retry a POST. Keys are scoped to the subject of the authenticated claims and are kept for
+the TTL. Reusing a key for a different request is responded to with a 422 and a retry arriving while
+the first request is in progress with a 409. Failed requests release the key so they can be retried.
+Requests without the header are handled as usual.
+
+The keys of unauthenticated requests are scoped to the client, identified by its address and user
+agent. Requests are told apart by an HMAC of their body so the stored keys reveal nothing about it.
+
+Credentials are not stored so a replay never hands out an expired token. Routes must not respond
+with credentials in the body.
+*/
+func Idempotency(log *zap.SugaredLogger, store IdempotencyStore, cfg IdempotencyConfig) Idempotent {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if len(cfg.Secret) == 0 {
+		cfg.Secret = make([]byte, 32)
+		if _, err := rand.Read(cfg.Secret); err != nil {
+			panic(fmt.Sprintf("generating idempotency secret: %v", err))
+		}
+	}
+
+	// The middleware of each route is built with the handler reissuing its credentials.
+	idempotent := func(reissue web.Handler) web.Middleware {
+
+		// This is the actual middleware function to be executed.
+		m := func(handler web.Handler) web.Handler {
+
+			// Create the handler that will be attached to the middleware chain.
+			h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				key := r.Header.Get(IdempotencyKeyHeader)
+				if key == "" {
+					return handler(ctx, w, r)
+				}
+				if len(key) > maxIdempotencyKeyLen {
+					err := fmt.Errorf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLen)
+					return validate.NewRequestError(err, http.StatusBadRequest)
+				}
+
+				// If the context is missing this value, request the service to be shutdown gracefully.
+				v, err := web.GetValues(ctx)
+				if err != nil {
+					return web.NewShutdownError("web value missing from context")
+				}
+
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					var maxErr *http.MaxBytesError
+					if errors.As(err, &maxErr) {
+						return web.NewError(fmt.Errorf("request body must not be larger than %d bytes", maxErr.Limit), http.StatusRequestEntityTooLarge)
+					}
+					return fmt.Errorf("reading body: %w", err)
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+
+				subject := "anonymous:" + client(cfg.Secret, r)
+				if claims, err := auth.GetClaims(ctx); err == nil {
+					subject = claims.Subject
+				}
+
+				nr := idempotency.NewRecord{
+					Key:         key,
+					Subject:     subject,
+					Fingerprint: fingerprint(cfg.Secret, r, body),
+				}
+
+				rec, replay, err := store.Begin(ctx, nr, cfg.TTL, v.Now)
+				if err != nil {
+					return err
+				}
+
+				if replay {
+					for name, values := range rec.Header {
+						w.Header()[http.CanonicalHeaderKey(name)] = values
+					}
+					if reissue != nil {
+						if err := reissue(ctx, w, r); err != nil {
+							return err
+						}
+					}
+					w.Header().Set("Idempotent-Replayed", "true")
+					web.SetStatusCode(ctx, rec.StatusCode)
+					w.WriteHeader(rec.StatusCode)
+					_, err := w.Write(rec.Body)
+					return err
+				}
+
+				cw := captureWriter{ResponseWriter: w}
+
+				// Call the next handler.
+				if err := handler(ctx, &cw, r); err != nil || cw.statusCode() >= http.StatusInternalServerError {
+					if rerr := store.Release(ctx, key, subject); rerr != nil {
+						log.Errorw("idempotency", "traceid", v.TracedID, "ERROR", rerr)
+					}
+					return err
+				}
+
+				resp := idempotency.Response{
+					StatusCode: cw.statusCode(),
+					Header:     make(map[string][]string),
+					Body:       cw.body.Bytes(),
+				}
+				for _, name := range replayHeaders {
+					if values := w.Header().Values(name); len(values) > 0 {
+						resp.Header[http.CanonicalHeaderKey(name)] = values
+					}
+				}
+
+				// The response has been sent, failing to store it only means a retry is executed again.
+				if err := store.Complete(ctx, key, subject, resp); err != nil {
+					log.Errorw("idempotency", "traceid", v.TracedID, "ERROR", err)
+					if rerr := store.Release(ctx, key, subject); rerr != nil {
+						log.Errorw("idempotency", "traceid", v.TracedID, "ERROR", rerr)
+					}
+				}
+
+				return nil
+			}
+			return h
+		}
+		return m
+	}
+	return idempotent
+}
+
+// fingerprint identifies the request so a key reused for a different request is detected.
+func fingerprint(secret []byte, r *http.Request, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// client identifies an unauthenticated client by its address and user agent.
+func client(secret []byte, r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	h := hmac.New(sha256.New, secret)
+	io.WriteString(h, host+"\n"+r.UserAgent())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// captureWriter keeps a copy of the status code and the body written to the response.
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code before writing it.
+func (cw *captureWriter) WriteHeader(statusCode int) {
+	if cw.status == 0 {
+		cw.status = statusCode
+	}
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write keeps a copy of the body before writing it.
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.body.Write(b)
+	return cw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// statusCode returns the status code written, no write means an implicit 200.
+func (cw *captureWriter) statusCode() int {
+	if cw.status == 0 {
+		return http.StatusOK
+	}
+	return cw.status
+}
@@ -0,0 +1,238 @@
+package mid_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/rdforte/go-service/business/core/idempotency"
+	"github.com/rdforte/go-service/business/sys/auth"
+	"github.com/rdforte/go-service/business/web/mid"
+	"github.com/rdforte/go-service/foundation/logger"
+	"github.com/rdforte/go-service/foundation/web"
+	"go.uber.org/zap"
+)
+
+// memoryStore is an in memory IdempotencyStore behaving like idempotency.Core.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[string]idempotency.Record)}
+}
+
+func (s *memoryStore) Begin(ctx context.Context, nr idempotency.NewRecord, ttl time.Duration, now time.Time) (idempotency.Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := nr.Key + "|" + nr.Subject
+	rec, exists := s.records[id]
+	switch {
+	case !exists:
+		rec = idempotency.Record{Key: nr.Key, Subject: nr.Subject, Fingerprint: nr.Fingerprint}
+		s.records[id] = rec
+		return rec, false, nil
+	case rec.Fingerprint != nr.Fingerprint:
+		return idempotency.Record{}, false, idempotency.ErrKeyReused
+	case !rec.Completed:
+		return idempotency.Record{}, false, idempotency.ErrInFlight
+	}
+	return rec, true, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, key string, subject string, resp idempotency.Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := key + "|" + subject
+	rec := s.records[id]
+	rec.StatusCode, rec.Header, rec.Body, rec.Completed = resp.StatusCode, resp.Header, resp.Body, true
+	s.records[id] = rec
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, key string, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key+"|"+subject)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	log := zap.NewNop().Sugar()
+
+	// reissue sets a new cookie on a replayed response.
+	reissue := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		http.SetCookie(w, &http.Cookie{Name: "token", Value: "reissued"})
+		return nil
+	}
+
+	// newApp mounts a signup like handler setting a cookie, optionally as an authenticated subject.
+	newApp := func(store mid.IdempotencyStore, subject string, calls *int, opts ...web.Option) *web.App {
+		idempotent := mid.Idempotency(log, store, mid.IdempotencyConfig{TTL: time.Hour, Secret: []byte("secret")})
+		app := web.NewApp(make(chan os.Signal, 1), []web.Middleware{mid.Errors(log)}, opts...)
+
+		authenticate := func(handler web.Handler) web.Handler {
+			return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				if subject != "" {
+					ctx = auth.SetClaims(ctx, auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: subject}})
+				}
+				return handler(ctx, w, r)
+			}
+		}
+
+		app.Post("/user/signup", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			*calls++
+			if strings.Contains(r.Header.Get("X-Fail"), "true") {
+				return errors.New("failed")
+			}
+			http.SetCookie(w, &http.Cookie{Name: "token", Value: fmt.Sprint("tok", *calls)})
+			return web.Respond(ctx, w, map[string]int{"call": *calls}, http.StatusCreated)
+		}, authenticate, idempotent(reissue))
+
+		return app
+	}
+
+	// post sends the request from the client at the address.
+	post := func(app *web.App, remoteAddr string, key string, body string, headers ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/user/signup", strings.NewReader(body))
+		r.RemoteAddr = remoteAddr
+		if key != "" {
+			r.Header.Set(mid.IdempotencyKeyHeader, key)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+
+	const bill, jill = "10.0.0.1:5000", "10.0.0.2:5000"
+
+	tl.Describe("Handling requests made with an Idempotency-Key")
+	{
+		tl.It("should replay the response to a retry and reissue the cookie")
+		{
+			store := newMemoryStore()
+			var calls int
+			app := newApp(store, "", &calls)
+
+			first := post(app, bill, "key", `{"email":"bill@example.com"}`)
+			retry := post(app, bill, "key", `{"email":"bill@example.com"}`)
+
+			if calls != 1 {
+				tl.Failed("Should execute the request once", fmt.Errorf("calls[%d]", calls))
+			}
+			tl.Success("Should execute the request once")
+
+			if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() ||
+				first.Header().Get("Set-Cookie") != "token=tok1" || retry.Header().Get("Set-Cookie") != "token=reissued" ||
+				retry.Header().Get("Content-Type") != "application/json" ||
+				retry.Header().Get("Idempotent-Replayed") != "true" {
+				tl.Failed("Should replay the response", fmt.Errorf("status[%d] body[%s] header[%v]", retry.Code, retry.Body, retry.Header()))
+			}
+			tl.Success("Should replay the response")
+
+			for _, rec := range store.records {
+				if _, ok := rec.Header["Set-Cookie"]; ok {
+					tl.Failed("Should not store the cookie", fmt.Errorf("header[%v]", rec.Header))
+				}
+			}
+			tl.Success("Should not store the cookie")
+		}
+
+		tl.It("should not share keys between unauthenticated clients")
+		{
+			var calls int
+			app := newApp(newMemoryStore(), "", &calls)
+
+			post(app, bill, "key", `{"email":"bill@example.com"}`)
+			other := post(app, jill, "key", `{"email":"jill@example.com"}`)
+
+			if other.Code != http.StatusCreated || calls != 2 || other.Header().Get("Idempotent-Replayed") != "" {
+				tl.Failed("Should execute the request of another client", fmt.Errorf("status[%d] calls[%d]", other.Code, calls))
+			}
+			tl.Success("Should execute the request of another client")
+		}
+
+		tl.It("should respond 422 when an unauthenticated client reuses a key for a different request")
+		{
+			store := newMemoryStore()
+			var calls int
+			app := newApp(store, "", &calls)
+
+			body := `{"email":"bill@example.com","password":"gophers"}`
+			post(app, bill, "key", body)
+			reused := post(app, bill, "key", `{"email":"bill@example.com","password":"other"}`)
+
+			if reused.Code != http.StatusUnprocessableEntity || calls != 1 {
+				tl.Failed("Should respond 422", fmt.Errorf("status[%d] calls[%d]", reused.Code, calls))
+			}
+			tl.Success("Should respond 422")
+
+			sum := sha256.Sum256([]byte("POST /user/signup\n" + body))
+			for _, rec := range store.records {
+				if rec.Fingerprint == hex.EncodeToString(sum[:]) || strings.Contains(rec.Subject, rec.Fingerprint) {
+					tl.Failed("Should only store an HMAC of the body", fmt.Errorf("record[%+v]", rec))
+				}
+			}
+			tl.Success("Should only store an HMAC of the body")
+		}
+
+		tl.It("should respond 422 when an authenticated subject reuses a key for a different request")
+		{
+			var calls int
+			app := newApp(newMemoryStore(), "5cf37266-3473-4006-984f-9325122678b7", &calls)
+
+			post(app, bill, "key", `{"name":"bill"}`)
+			reused := post(app, bill, "key", `{"name":"jill"}`)
+
+			if reused.Code != http.StatusUnprocessableEntity || calls != 1 {
+				tl.Failed("Should respond 422", fmt.Errorf("status[%d] calls[%d]", reused.Code, calls))
+			}
+			tl.Success("Should respond 422")
+		}
+
+		tl.It("should release the key of a failed request so it can be retried")
+		{
+			var calls int
+			app := newApp(newMemoryStore(), "", &calls)
+
+			failed := post(app, bill, "key", `{}`, "X-Fail", "true")
+			retry := post(app, bill, "key", `{}`)
+
+			if failed.Code != http.StatusInternalServerError || retry.Code != http.StatusCreated || calls != 2 {
+				tl.Failed("Should execute the retry", fmt.Errorf("failed[%d] retry[%d] calls[%d]", failed.Code, retry.Code, calls))
+			}
+			tl.Success("Should execute the retry")
+		}
+
+		tl.It("should respond 413 to a body larger than the limit")
+		{
+			var calls int
+			app := newApp(newMemoryStore(), "", &calls, web.WithMaxBodyBytes(10))
+
+			w := post(app, bill, "key", strings.Repeat("x", 100))
+			if w.Code != http.StatusRequestEntityTooLarge {
+				tl.Failed("Should respond 413", fmt.Errorf("status[%d] body[%s]", w.Code, w.Body))
+			}
+			tl.Success("Should respond 413")
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package lifecycle orchestrates the start up and graceful shutdown of the components
+// which make up a service such as servers, background workers and database pools.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Hook describes how to start and stop a component. Start must not block, long running
+// work is started on a goroutine using Manager.Go. Timeout bounds both Start and Stop and
+// a zero Timeout relies on the deadline of the context provided to the Manager.
+type Hook struct {
+	Name    string
+	Start   func(ctx context.Context) error
+	Stop    func(ctx context.Context) error
+	Timeout time.Duration
+}
+
+// Option configures optional behaviour of a Manager.
+type Option func(m *Manager)
+
+// WithDrainDelay sets how long the Manager waits after flipping to not ready before it
+// stops the components. This gives load balancers time to stop sending new requests.
+func WithDrainDelay(d time.Duration) Option {
+	return func(m *Manager) {
+		m.drainDelay = d
+	}
+}
+
+// Manager starts the registered components in the order they were appended and stops them in the
+// reverse order, so a component is always stopped before the components it depends on. The Manager
+// is ready once every component has started and becomes not ready as soon as the shutdown begins.
+type Manager struct {
+	mu         sync.Mutex
+	hooks      []Hook
+	started    int
+	ready      atomic.Bool
+	drainDelay time.Duration
+	errors     chan error
+}
+
+// New constructs a Manager ready for components to be appended.
+func New(opts ...Option) *Manager {
+	m := Manager{
+		errors: make(chan error, 1),
+	}
+
+	for _, opt := range opts {
+		opt(&m)
+	}
+
+	return &m
+}
+
+// Append registers a component with the Manager. Components must be appended before Start.
+func (m *Manager) Append(h Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, h)
+}
+
+// Start starts the components in order. If a component fails to start the components which
+// have already started are stopped and the errors are returned together.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, h := range m.hooks {
+		if h.Start != nil {
+			if err := run(ctx, h.Timeout, h.Start); err != nil {
+				err = fmt.Errorf("starting %s: %w", h.Name, err)
+				return errors.Join(err, m.stop(ctx))
+			}
+		}
+		m.started++
+	}
+
+	m.ready.Store(true)
+	return nil
+}
+
+// Stop flips the Manager to not ready, waits for the drain delay and then stops the started
+// components in reverse order. Every component is given the chance to stop and the errors of
+// the components which failed to stop are returned together.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.ready.Store(false)
+
+	if m.drainDelay > 0 {
+		select {
+		case <-time.After(m.drainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stop(ctx)
+}
+
+// Ready reports whether every component has started and the shutdown has not begun.
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// Go runs fn on a goroutine for a component. An error returned by fn is reported on Errors
+// so the service can begin its shutdown.
+func (m *Manager) Go(name string, fn func() error) {
+	go func() {
+		if err := fn(); err != nil {
+			select {
+			case m.errors <- fmt.Errorf("%s: %w", name, err):
+			default:
+			}
+		}
+	}()
+}
+
+// Errors returns the channel on which failures of the components run with Go are reported.
+// Only the first failure is kept since any failure begins the shutdown.
+func (m *Manager) Errors() <-chan error {
+	return m.errors
+}
+
+// stop stops the started components in reverse order. The caller must hold the lock.
+func (m *Manager) stop(ctx context.Context) error {
+	var errs []error
+	for ; m.started > 0; m.started-- {
+		h := m.hooks[m.started-1]
+		if h.Stop == nil {
+			continue
+		}
+
+		if err := run(ctx, h.Timeout, h.Stop); err != nil {
+			errs = append(errs, fmt.Errorf("stopping %s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run calls fn with a context bounded by the timeout.
+func run(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return fn(ctx)
+}
@@ -0,0 +1,108 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdforte/go-service/foundation/lifecycle"
+	"github.com/rdforte/go-service/foundation/logger"
+)
+
+func TestManager(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Starting and stopping the components of a service")
+	{
+		tl.It("should stop the components in reverse order and aggregate the errors")
+		{
+			var calls []string
+			m := lifecycle.New()
+
+			for _, name := range []string{"db", "debug", "api"} {
+				m.Append(hook(&calls, name, nil))
+			}
+			m.Append(lifecycle.Hook{
+				Name: "worker",
+				Stop: func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+				Timeout: time.Millisecond,
+			})
+			m.Append(hook(&calls, "cache", errors.New("flush failed")))
+
+			if err := m.Start(context.Background()); err != nil {
+				tl.Failed("Should start the components", err)
+			}
+			tl.Success("Should start the components")
+
+			if !m.Ready() {
+				tl.Failed("Should be ready once started", errors.New("not ready"))
+			}
+			tl.Success("Should be ready once started")
+
+			calls = nil
+			err := m.Stop(context.Background())
+
+			if m.Ready() {
+				tl.Failed("Should not be ready once stopped", errors.New("ready"))
+			}
+			tl.Success("Should not be ready once stopped")
+
+			if strings.Join(calls, ",") != "stop cache,stop api,stop debug,stop db" {
+				tl.Failed("Should stop in reverse order", fmt.Errorf("calls%v", calls))
+			}
+			tl.Success("Should stop in reverse order")
+
+			if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "stopping cache: flush failed") {
+				tl.Failed("Should return the errors of every component", err)
+			}
+			tl.Success("Should return the errors of every component")
+		}
+
+		tl.It("should stop the started components when a component fails to start")
+		{
+			var calls []string
+			m := lifecycle.New()
+
+			m.Append(hook(&calls, "db", nil))
+			m.Append(lifecycle.Hook{
+				Name: "api",
+				Start: func(ctx context.Context) error {
+					return errors.New("address in use")
+				},
+			})
+			m.Append(hook(&calls, "worker", nil))
+
+			err := m.Start(context.Background())
+			if err == nil || m.Ready() {
+				tl.Failed("Should fail to start", err)
+			}
+			tl.Success("Should fail to start")
+
+			if strings.Join(calls, ",") != "start db,stop db" {
+				tl.Failed("Should stop the started components", fmt.Errorf("calls%v", calls))
+			}
+			tl.Success("Should stop the started components")
+		}
+	}
+}
+
+// hook returns a Hook recording its calls which fails to stop with the provided error.
+func hook(calls *[]string, name string, stopErr error) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			*calls = append(*calls, "start "+name)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			*calls = append(*calls, "stop "+name)
+			return stopErr
+		},
+	}
+}
@@ -3,25 +3,188 @@
 package logger
 
 import (
+	"fmt"
+	"sync"
+	"time"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// Root is the component name of the logger returned by New.
+const Root = "root"
+
+// DefaultLevel is the level every logger starts at.
+const DefaultLevel = zapcore.InfoLevel
+
 // New constructs a Sugared Logger that writes to stdout and
-// provides human readable timestamps.
-func New(service string) (*zap.SugaredLogger, error) {
-	config := zap.NewProductionConfig()
-	config.OutputPaths = []string{"stdout"}
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	config.DisableStacktrace = true
-	config.InitialFields = map[string]interface{}{
+// provides human readable timestamps. The returned Levels controls
+// the level of the logger and its named components at runtime.
+// Sensitive fields are always redacted before they are written.
+func New(service string, opts ...Option) (*zap.SugaredLogger, *Levels, error) {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	levels := Levels{
+		levels: map[string]zap.AtomicLevel{
+			Root: zap.NewAtomicLevelAt(DefaultLevel),
+		},
+		timers: make(map[string]*time.Timer),
+	}
+
+	zc := zap.NewProductionConfig()
+	zc.OutputPaths = []string{"stdout"}
+	zc.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	zc.DisableStacktrace = true
+	zc.InitialFields = map[string]interface{}{
 		"service": service,
 	}
 
-	log, err := config.Build()
+	// The core lets every level through so the level of each component can be lowered
+	// independently of the root logger.
+	zc.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+	// Only the configured messages are sampled rather than every message.
+	zc.Sampling = nil
+
+	wrap := zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		core = &redactCore{Core: core}
+		if cfg.sampling != nil {
+			core = newSampleCore(core, *cfg.sampling)
+		}
+		return &levelCore{Core: core, level: levels.levels[Root]}
+	})
+
+	log, err := zc.Build(wrap)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	return log.Sugar(), &levels, nil
+}
+
+// Levels controls the levels of the root logger and its named components at runtime.
+type Levels struct {
+	mu     sync.Mutex
+	levels map[string]zap.AtomicLevel
+	timers map[string]*time.Timer
+}
+
+// Named returns a logger for the component with a level independent of the root logger.
+// Loggers for the same component share their level. A nil Levels, as when testing with a
+// logger not constructed by New, returns the named logger as is.
+func (l *Levels) Named(log *zap.SugaredLogger, component string) *zap.SugaredLogger {
+	if l == nil {
+		return log.Named(component)
+	}
+
+	l.mu.Lock()
+	level, ok := l.levels[component]
+	if !ok {
+		level = zap.NewAtomicLevelAt(DefaultLevel)
+		l.levels[component] = level
+	}
+	l.mu.Unlock()
+
+	wrap := zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if lc, ok := core.(*levelCore); ok {
+			core = lc.Core
+		}
+		return &levelCore{Core: core, level: level}
+	})
+
+	return log.Desugar().Named(component).WithOptions(wrap).Sugar()
+}
+
+// Level returns the atomic level of the component, use Root for the logger returned by New.
+func (l *Levels) Level(component string) (zap.AtomicLevel, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	level, ok := l.levels[component]
+	if !ok {
+		return zap.AtomicLevel{}, fmt.Errorf("unknown logger component[%s]", component)
+	}
+	return level, nil
+}
+
+// Levels returns the current level of every component.
+func (l *Levels) Levels() map[string]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	levels := make(map[string]string, len(l.levels))
+	for component, level := range l.levels {
+		levels[component] = level.String()
+	}
+	return levels
+}
+
+// SetLevel sets the level of the component. When the ttl is greater than zero the
+// component reverts to the DefaultLevel once the ttl has passed.
+func (l *Levels) SetLevel(component string, level zapcore.Level, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lvl, ok := l.levels[component]
+	if !ok {
+		return fmt.Errorf("unknown logger component[%s]", component)
 	}
 
-	return log.Sugar(), nil
+	if timer, ok := l.timers[component]; ok {
+		timer.Stop()
+		delete(l.timers, component)
+	}
+
+	lvl.SetLevel(level)
+
+	if ttl > 0 {
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			// The level may have been set again while waiting for the lock.
+			if l.timers[component] != timer {
+				return
+			}
+
+			lvl.SetLevel(DefaultLevel)
+			delete(l.timers, component)
+		})
+		l.timers[component] = timer
+	}
+
+	return nil
+}
+
+// levelCore filters the entries written to the core by its own level.
+type levelCore struct {
+	zapcore.Core
+	level zap.AtomicLevel
+}
+
+// Enabled reports whether the level is enabled for the core.
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+// Level returns the current level of the core.
+func (c *levelCore) Level() zapcore.Level {
+	return c.level.Level()
+}
+
+// With adds the fields to the core keeping its level.
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+// Check adds the core to the checked entry when the level of the entry is enabled.
+func (c *levelCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.level.Enabled(entry.Level) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
 }
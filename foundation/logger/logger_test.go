@@ -0,0 +1,110 @@
+package logger_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rdforte/go-service/foundation/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevels(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Changing the log levels at runtime")
+	{
+		tl.It("should set the level of a component independently of the root logger")
+		{
+			log, levels, err := logger.New("TEST")
+			if err != nil {
+				tl.Failed("Should construct the logger", err)
+			}
+			tl.Success("Should construct the logger")
+
+			named := levels.Named(log, "user")
+
+			if err := levels.SetLevel("user", zapcore.DebugLevel, 0); err != nil {
+				tl.Failed("Should set the level of the component", err)
+			}
+			tl.Success("Should set the level of the component")
+
+			if !named.Desugar().Core().Enabled(zapcore.DebugLevel) || log.Desugar().Core().Enabled(zapcore.DebugLevel) {
+				tl.Failed("Should only enable debug logs for the component", fmt.Errorf("levels%v", levels.Levels()))
+			}
+			tl.Success("Should only enable debug logs for the component")
+
+			if err := levels.SetLevel("unknown", zapcore.DebugLevel, 0); err == nil {
+				tl.Failed("Should not set the level of an unknown component", errors.New("no error"))
+			}
+			tl.Success("Should not set the level of an unknown component")
+		}
+
+		tl.It("should revert to the default level after the ttl")
+		{
+			log, levels, err := logger.New("TEST")
+			if err != nil {
+				tl.Failed("Should construct the logger", err)
+			}
+
+			if err := levels.SetLevel(logger.Root, zapcore.DebugLevel, 10*time.Millisecond); err != nil {
+				tl.Failed("Should set the level of the root logger", err)
+			}
+
+			if !log.Desugar().Core().Enabled(zapcore.DebugLevel) {
+				tl.Failed("Should enable debug logs", fmt.Errorf("levels%v", levels.Levels()))
+			}
+			tl.Success("Should enable debug logs")
+
+			time.Sleep(50 * time.Millisecond)
+
+			if log.Desugar().Core().Enabled(zapcore.DebugLevel) {
+				tl.Failed("Should revert to the default level", fmt.Errorf("levels%v", levels.Levels()))
+			}
+			tl.Success("Should revert to the default level")
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Redacting sensitive fields")
+	{
+		tl.It("should redact fields by tag and by name")
+		{
+			type user struct {
+				ID           string    `db:"user_id"`
+				Phone        string    `db:"phone" log:"redact"`
+				PasswordHash []byte    `db:"password_hash"`
+				DateCreated  time.Time `db:"date_created"`
+			}
+
+			now := time.Now()
+			got := logger.Redact(user{ID: "1", Phone: "555", PasswordHash: []byte("hash"), DateCreated: now}, "db")
+
+			exp := map[string]interface{}{
+				"user_id":       "1",
+				"phone":         logger.Redacted,
+				"password_hash": logger.Redacted,
+				"date_created":  now,
+			}
+			if fmt.Sprint(got) != fmt.Sprint(exp) {
+				tl.Failed("Should redact the struct fields", fmt.Errorf("got%v", got))
+			}
+			tl.Success("Should redact the struct fields")
+
+			got = logger.Redact(map[string]interface{}{"name": "gopher", "email": "gopher@example.com"}, "db")
+
+			exp = map[string]interface{}{
+				"name":  "gopher",
+				"email": logger.Redacted,
+			}
+			if fmt.Sprint(got) != fmt.Sprint(exp) {
+				tl.Failed("Should redact the map keys", fmt.Errorf("got%v", got))
+			}
+			tl.Success("Should redact the map keys")
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces the value of a sensitive field.
+const Redacted = "[REDACTED]"
+
+// redactTag marks a struct field as sensitive ie: `log:"redact"`.
+const redactTag = "redact"
+
+// redacted is the set of field names which are always redacted. The names are matched
+// without case and ignoring underscores so password_hash also matches PasswordHash.
+var redacted = struct {
+	sync.RWMutex
+	names map[string]bool
+}{
+	names: map[string]bool{
+		"password":      true,
+		"passwordhash":  true,
+		"email":         true,
+		"token":         true,
+		"secret":        true,
+		"authorization": true,
+	},
+}
+
+// RegisterRedacted adds field names which are always redacted.
+func RegisterRedacted(names ...string) {
+	redacted.Lock()
+	defer redacted.Unlock()
+
+	for _, name := range names {
+		redacted.names[normalize(name)] = true
+	}
+}
+
+// IsRedacted reports whether the value of the field name is redacted.
+func IsRedacted(name string) bool {
+	redacted.RLock()
+	defer redacted.RUnlock()
+
+	return redacted.names[normalize(name)]
+}
+
+// Redact returns the value with its sensitive fields replaced by Redacted. A field is sensitive when
+// its name is registered or it is tagged with `log:"redact"`. Structs are converted into a map keyed
+// by the name in the provided tag (ie: "json" or "db") falling back to the field name, and maps with
+// string keys are matched by key. Other values, including types marshaling themselves, are returned as is.
+func Redact(v interface{}, tag string) interface{} {
+	if v == nil {
+		return nil
+	}
+	return redactValue(reflect.ValueOf(v), tag)
+}
+
+// redactValue redacts the value walking into structs, maps and slices.
+func redactValue(val reflect.Value, tag string) interface{} {
+	if !val.IsValid() {
+		return nil
+	}
+
+	// Types which marshal themselves, such as time.Time, are left alone.
+	if val.CanInterface() {
+		switch val.Interface().(type) {
+		case json.Marshaler, encoding.TextMarshaler:
+			return val.Interface()
+		}
+	}
+
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			return nil
+		}
+		return redactValue(val.Elem(), tag)
+
+	case reflect.Struct:
+		fields := make(map[string]interface{}, val.NumField())
+		redactStruct(val, tag, fields)
+		return fields
+
+	case reflect.Map:
+		if val.Type().Key().Kind() != reflect.String {
+			break
+		}
+		fields := make(map[string]interface{}, val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			name := iter.Key().String()
+			if IsRedacted(name) {
+				fields[name] = Redacted
+				continue
+			}
+			fields[name] = redactValue(iter.Value(), tag)
+		}
+		return fields
+
+	case reflect.Slice, reflect.Array:
+		switch val.Type().Elem().Kind() {
+		case reflect.Struct, reflect.Ptr, reflect.Interface, reflect.Map:
+			items := make([]interface{}, val.Len())
+			for i := range items {
+				items[i] = redactValue(val.Index(i), tag)
+			}
+			return items
+		}
+	}
+
+	if !val.CanInterface() {
+		return nil
+	}
+	return val.Interface()
+}
+
+// redactStruct adds the exported fields of the struct to the map. The fields of embedded
+// structs are added as if they belonged to the outer struct.
+func redactStruct(val reflect.Value, tag string, fields map[string]interface{}) {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			redactStruct(val.Field(i), tag, fields)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if field.Tag.Get("log") == redactTag || IsRedacted(name) || IsRedacted(field.Name) {
+			fields[name] = Redacted
+			continue
+		}
+
+		fields[name] = redactValue(val.Field(i), tag)
+	}
+}
+
+// normalize converts the name into the form used to match redacted names.
+func normalize(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// =============================================================================
+
+// redactCore redacts the fields before they are written to the core.
+type redactCore struct {
+	zapcore.Core
+}
+
+// With adds the redacted fields to the core.
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(redactFields(fields))}
+}
+
+// Check adds this core to the checked entry so the fields pass through Write.
+func (c *redactCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+// Write writes the entry with the redacted fields.
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+// redactFields returns the fields with the values of sensitive fields replaced.
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch {
+		case IsRedacted(f.Key):
+			out[i] = zap.String(f.Key, Redacted)
+		case f.Type == zapcore.ReflectType:
+			out[i] = zap.Any(f.Key, Redact(f.Interface, "json"))
+		default:
+			out[i] = f
+		}
+	}
+	return out
+}
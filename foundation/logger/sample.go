@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Sampling configures the sampling of high volume messages. Within each Tick the first
+// entries of a message are logged and after that only every Thereafter entry is logged.
+type Sampling struct {
+	Messages   []string
+	Tick       time.Duration
+	First      int
+	Thereafter int
+}
+
+// Option configures optional behaviour of the logger constructed by New.
+type Option func(cfg *config)
+
+// config holds the optional behaviour of the logger.
+type config struct {
+	sampling *Sampling
+}
+
+// WithSampling samples the configured messages. Messages which are not configured are
+// always logged.
+func WithSampling(s Sampling) Option {
+	return func(cfg *config) {
+		cfg.sampling = &s
+	}
+}
+
+// sampleCore samples the entries of the configured messages and passes all other entries
+// straight through to the core.
+type sampleCore struct {
+	zapcore.Core
+	sampler  zapcore.Core
+	messages map[string]bool
+}
+
+// newSampleCore wraps the core sampling the configured messages.
+func newSampleCore(core zapcore.Core, s Sampling) zapcore.Core {
+	messages := make(map[string]bool, len(s.Messages))
+	for _, msg := range s.Messages {
+		messages[msg] = true
+	}
+
+	return &sampleCore{
+		Core:     core,
+		sampler:  zapcore.NewSamplerWithOptions(core, s.Tick, s.First, s.Thereafter),
+		messages: messages,
+	}
+}
+
+// With adds the fields to the core keeping the sampling state.
+func (c *sampleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sampleCore{
+		Core:     c.Core.With(fields),
+		sampler:  c.sampler.With(fields),
+		messages: c.messages,
+	}
+}
+
+// Check samples the entry when its message is configured.
+func (c *sampleCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.messages[entry.Message] {
+		return c.sampler.Check(entry, ce)
+	}
+	return c.Core.Check(entry, ce)
+}
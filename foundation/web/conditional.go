@@ -0,0 +1,132 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// conditionsKey is how the conditions of the request are stored/retrieved.
+const conditionsKey ctxKey = 3
+
+// conditions are the validators of a conditional request which Respond evaluates.
+type conditions struct {
+	method          string
+	ifNoneMatch     string
+	ifModifiedSince string
+}
+
+// newConditions captures the validators of the request.
+func newConditions(r *http.Request) conditions {
+	return conditions{
+		method:          r.Method,
+		ifNoneMatch:     r.Header.Get("If-None-Match"),
+		ifModifiedSince: r.Header.Get("If-Modified-Since"),
+	}
+}
+
+// SetETag sets the ETag of the response from the version of the resource. Without it Respond
+// hashes the body to create the ETag.
+func SetETag(w http.ResponseWriter, version string) {
+	w.Header().Set("ETag", `"`+version+`"`)
+}
+
+// SetLastModified sets the Last-Modified time of the response.
+func SetLastModified(w http.ResponseWriter, t time.Time) {
+	w.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// codings are the content codings CodingETag suffixes an ETag with.
+var codings = []string{"gzip", "deflate"}
+
+// CodingETag returns the ETag of the representation encoded with the content coding. The encoded
+// bytes differ from the bytes of a strong ETag, so the coding is added to keep it strong and
+// distinct. Weak tags are returned as is. The coding is removed again when matching the tag.
+func CodingETag(etag string, coding string) string {
+	if strings.HasPrefix(etag, "W/") || len(etag) < 2 || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + coding + `"`
+}
+
+// trimCoding removes the content coding CodingETag added to the tag.
+func trimCoding(tag string) string {
+	for _, coding := range codings {
+		if suffix := "-" + coding + `"`; strings.HasSuffix(tag, suffix) {
+			return tag[:len(tag)-len(suffix)] + `"`
+		}
+	}
+	return tag
+}
+
+// IfMatch returns the versions in the If-Match header of the request, the reverse of SetETag.
+// If-Match uses the strong comparison so weak tags are returned as is and never match a version.
+// No versions are returned when the header is missing or matches any version (*).
+func IfMatch(r *http.Request) []string {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	var versions []string
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, `"`) && strings.HasSuffix(tag, `"`) && len(tag) > 1 {
+			tag = trimCoding(tag)
+			tag = tag[1 : len(tag)-1]
+		}
+		versions = append(versions, tag)
+	}
+	return versions
+}
+
+// notModified sets the ETag of a successful GET or HEAD response and reports whether the client
+// already has the current representation of the resource.
+func notModified(ctx context.Context, h http.Header, body []byte) bool {
+	c, ok := ctx.Value(conditionsKey).(conditions)
+	if !ok || (c.method != http.MethodGet && c.method != http.MethodHead) {
+		return false
+	}
+
+	if h.Get("ETag") == "" {
+		sum := sha256.Sum256(body)
+		h.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since when both are provided.
+	if c.ifNoneMatch != "" {
+		return matchETag(c.ifNoneMatch, h.Get("ETag"))
+	}
+
+	if c.ifModifiedSince != "" && h.Get("Last-Modified") != "" {
+		since, err := http.ParseTime(c.ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		modified, err := http.ParseTime(h.Get("Last-Modified"))
+		if err != nil {
+			return false
+		}
+		return !modified.After(since)
+	}
+
+	return false
+}
+
+// matchETag reports whether the etag is in the list of tags using the weak comparison.
+func matchETag(tags string, etag string) bool {
+	if strings.TrimSpace(tags) == "*" {
+		return true
+	}
+
+	etag = trimCoding(strings.TrimPrefix(etag, "W/"))
+	for _, tag := range strings.Split(tags, ",") {
+		if trimCoding(strings.TrimPrefix(strings.TrimSpace(tag), "W/")) == etag {
+			return true
+		}
+	}
+	return false
+}
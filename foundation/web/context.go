@@ -12,11 +12,18 @@ type ctxKey int
 // key is how request values are stored/retrieved.
 const key ctxKey = 1
 
+// routerKey is how the router that matched the request is stored/retrieved.
+const routerKey ctxKey = 2
+
 // Values represent state for each request.
 type Values struct {
 	TracedID   string
 	Now        time.Time
 	StatusCode int
+
+	// Route is the path template which matched the request ie: /v1/user/{id}. It is
+	// empty when no route matched.
+	Route string
 }
 
 // GetValues returns the values from the context.
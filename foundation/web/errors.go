@@ -0,0 +1,26 @@
+package web
+
+import "net/http"
+
+// Error is an error detected by the framework while processing a request. It carries the
+// HTTP status that best describes the failure and, when known, the request field at fault.
+type Error struct {
+	Err    error
+	Status int
+	Field  string
+}
+
+// Error implements the error interface.
+func (err *Error) Error() string {
+	return err.Err.Error()
+}
+
+// NewError wraps a provided error with an HTTP status code.
+func NewError(err error, status int) error {
+	return &Error{Err: err, Status: status}
+}
+
+// newFieldError wraps a provided error with a 400 status code and the request field at fault.
+func newFieldError(err error, field string) error {
+	return &Error{Err: err, Status: http.StatusBadRequest, Field: field}
+}
@@ -0,0 +1,77 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+/**
+Group is a set of routes sharing a path prefix and middleware. Groups can be nested in which case the
+prefixes are joined and the middleware of the parent group is executed prior to the middleware of the
+child group. The app specific middleware is always executed first and handler specific middleware last.
+*/
+type Group struct {
+	app    *App
+	prefix string
+	mw     []Middleware
+}
+
+// Group creates a group of routes mounted under the prefix ie: "v1" mounts routes under /v1.
+func (a *App) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		app:    a,
+		prefix: cleanPrefix(prefix),
+		mw:     mw,
+	}
+}
+
+// Group creates a nested group of routes inheriting the prefix and middleware of the group.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		app:    g.app,
+		prefix: g.prefix + cleanPrefix(prefix),
+		mw:     joinMiddleware(g.mw, mw),
+	}
+}
+
+// Get registers a handler for http GET requests within the group.
+func (g *Group) Get(path string, handler Handler, mw ...Middleware) {
+	g.app.handleReq(g.prefix+path, http.MethodGet, handler, joinMiddleware(g.mw, mw)...)
+}
+
+// Post registers a handler for http POST requests within the group.
+func (g *Group) Post(path string, handler Handler, mw ...Middleware) {
+	g.app.handleReq(g.prefix+path, http.MethodPost, handler, joinMiddleware(g.mw, mw)...)
+}
+
+// Patch registers a handler for http PATCH requests within the group.
+func (g *Group) Patch(path string, handler Handler, mw ...Middleware) {
+	g.app.handleReq(g.prefix+path, http.MethodPatch, handler, joinMiddleware(g.mw, mw)...)
+}
+
+// Put registers a handler for http PUT requests within the group.
+func (g *Group) Put(path string, handler Handler, mw ...Middleware) {
+	g.app.handleReq(g.prefix+path, http.MethodPut, handler, joinMiddleware(g.mw, mw)...)
+}
+
+// Delete registers a handler for http DELETE requests within the group.
+func (g *Group) Delete(path string, handler Handler, mw ...Middleware) {
+	g.app.handleReq(g.prefix+path, http.MethodDelete, handler, joinMiddleware(g.mw, mw)...)
+}
+
+// cleanPrefix normalises a prefix to have a leading slash and no trailing slash.
+func cleanPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+// joinMiddleware returns a new slice with the outer middleware followed by the inner middleware.
+// A new slice is always returned so groups never share a backing array.
+func joinMiddleware(outer []Middleware, inner []Middleware) []Middleware {
+	mw := make([]Middleware, 0, len(outer)+len(inner))
+	mw = append(mw, outer...)
+	return append(mw, inner...)
+}
@@ -2,27 +2,124 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
-
-	"github.com/gorilla/mux"
+	"strings"
 )
 
-// Param returns the web call parameters from the request.
+// DefaultMaxBodyBytes is the default limit on the size of a request body.
+const DefaultMaxBodyBytes = 1 << 20
+
+// validator is implemented by values that know how to validate themselves once decoded.
+type validator interface {
+	Validate() error
+}
+
+// Param returns the web call parameters from the request regardless of the router
+// backing the App.
 func Param(r *http.Request, key string) string {
-	v := mux.Vars(r)
-	return v[key]
+	rt, ok := r.Context().Value(routerKey).(router)
+	if !ok {
+		return ""
+	}
+	return rt.param(r, key)
 }
 
-// Decode reads the body of an HTTP request looking for a JSON document. The
+// Decode reads the body of an HTTP request looking for a single JSON document. The
 // body is decoded into the provided value.
 //
-// If the provided value is a struct then it is checked for validation tags.
+// If the provided value implements a Validate method it is called once decoding is
+// successful. Decoding failures are returned as an *Error with the status best
+// describing the problem and, when known, the offending field. Fields the value does
+// not know are ignored.
 func Decode(r *http.Request, val interface{}) error {
+	return decode(r, val, false)
+}
+
+// DecodeStrict is Decode rejecting fields the value does not know, for handlers which
+// would rather tell clients about a misspelled field than silently ignore it.
+func DecodeStrict(r *http.Request, val interface{}) error {
+	return decode(r, val, true)
+}
+
+// decode reads the body of the request into the value, rejecting unknown fields when strict.
+func decode(r *http.Request, val interface{}, strict bool) error {
+	if !isJSON(r.Header.Get("Content-Type")) {
+		return NewError(errors.New("content type must be application/json"), http.StatusUnsupportedMediaType)
+	}
+
 	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
 	if err := decoder.Decode(val); err != nil {
-		return err
+		return decodeError(err)
+	}
+
+	// Anything other than whitespace after the first document is rejected.
+	var extra json.RawMessage
+	if err := decoder.Decode(&extra); err != io.EOF {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return decodeError(err)
+		}
+		return NewError(errors.New("request body must only contain a single JSON document"), http.StatusBadRequest)
+	}
+
+	if v, ok := val.(validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// isJSON reports whether the content type is application/json or a +json suffixed type.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeError converts an error from the JSON decoder into an *Error pinpointing the problem.
+func decodeError(err error) error {
+	var maxErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var invalidErr *json.InvalidUnmarshalError
+
+	switch {
+	case errors.As(err, &maxErr):
+		return NewError(fmt.Errorf("request body must not be larger than %d bytes", maxErr.Limit), http.StatusRequestEntityTooLarge)
+
+	case errors.As(err, &syntaxErr):
+		return NewError(fmt.Errorf("request body contains malformed JSON at position %d", syntaxErr.Offset), http.StatusBadRequest)
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return NewError(errors.New("request body contains malformed JSON"), http.StatusBadRequest)
+
+	case errors.Is(err, io.EOF):
+		return NewError(errors.New("request body must not be empty"), http.StatusBadRequest)
+
+	case errors.As(err, &typeErr):
+		return newFieldError(fmt.Errorf("%s must be of type %s", typeErr.Field, typeErr.Type), typeErr.Field)
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// The decoder does not provide a typed error for unknown fields.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return newFieldError(fmt.Errorf("%s is not a known field", field), field)
+
+	case errors.As(err, &invalidErr):
+		// A non pointer value was provided which is a programming error.
+		return err
+
+	default:
+		return NewError(err, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,121 @@
+package web_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rdforte/go-service/foundation/logger"
+	"github.com/rdforte/go-service/foundation/web"
+)
+
+type payload struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// Validate rejects a payload without a name.
+func (p payload) Validate() error {
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func TestDecode(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Decoding JSON request bodies")
+	{
+		tl.It("should decode and validate a single JSON document")
+		{
+			r := newRequest("application/json; charset=utf-8", `{"name":"gopher","age":12}`, 0)
+
+			var p payload
+			if err := web.Decode(r, &p); err != nil {
+				tl.Failed("Should be able to decode the body", err)
+			}
+			if p.Name != "gopher" || p.Age != 12 {
+				tl.Failed("Should be able to decode the body", fmt.Errorf("payload: %+v", p))
+			}
+			tl.Success("Should be able to decode the body")
+		}
+
+		tl.It("should reject invalid bodies with the status and field at fault")
+		{
+			tests := []struct {
+				name        string
+				contentType string
+				body        string
+				limit       int64
+				status      int
+				field       string
+			}{
+				{"content type", "text/plain", `{"name":"gopher"}`, 0, http.StatusUnsupportedMediaType, ""},
+				{"too large", "application/json", `{"name":"gopher"}`, 8, http.StatusRequestEntityTooLarge, ""},
+				{"empty", "application/json", ``, 0, http.StatusBadRequest, ""},
+				{"malformed", "application/json", `{"name":`, 0, http.StatusBadRequest, ""},
+				{"syntax", "application/json", `{"name" "gopher"}`, 0, http.StatusBadRequest, ""},
+				{"multiple documents", "application/json", `{"name":"gopher"} {"name":"gopher"}`, 0, http.StatusBadRequest, ""},
+				{"trailing garbage", "application/json", `{"name":"gopher"} garbage`, 0, http.StatusBadRequest, ""},
+				{"wrong type", "application/json", `{"name":"gopher","age":"12"}`, 0, http.StatusBadRequest, "age"},
+			}
+
+			for _, tt := range tests {
+				var p payload
+				err := web.Decode(newRequest(tt.contentType, tt.body, tt.limit), &p)
+
+				var webErr *web.Error
+				if !errors.As(err, &webErr) {
+					tl.Failed("Should return a web error", fmt.Errorf("%s: %v", tt.name, err))
+				}
+				if webErr.Status != tt.status || webErr.Field != tt.field {
+					tl.Failed("Should return the expected status and field", fmt.Errorf("%s: status[%d] field[%s]", tt.name, webErr.Status, webErr.Field))
+				}
+			}
+			tl.Success("Should return the expected status and field")
+		}
+
+		tl.It("should only reject unknown fields when decoding strictly")
+		{
+			body := `{"name":"gopher","color":"blue"}`
+
+			var p payload
+			if err := web.Decode(newRequest("application/json", body, 0), &p); err != nil || p.Name != "gopher" {
+				tl.Failed("Should ignore unknown fields", fmt.Errorf("err[%v] payload[%+v]", err, p))
+			}
+			tl.Success("Should ignore unknown fields")
+
+			err := web.DecodeStrict(newRequest("application/json", body, 0), &p)
+
+			var webErr *web.Error
+			if !errors.As(err, &webErr) || webErr.Status != http.StatusBadRequest || webErr.Field != "color" {
+				tl.Failed("Should reject unknown fields", fmt.Errorf("err: %v", err))
+			}
+			tl.Success("Should reject unknown fields")
+		}
+
+		tl.It("should return the validation error of the value")
+		{
+			var p payload
+			err := web.Decode(newRequest("application/json", `{"age":12}`, 0), &p)
+			if err == nil || err.Error() != "name is required" {
+				tl.Failed("Should return the validation error", fmt.Errorf("err: %v", err))
+			}
+			tl.Success("Should return the validation error")
+		}
+	}
+}
+
+// newRequest constructs a request with the body limited to the provided number of bytes.
+func newRequest(contentType string, body string, limit int64) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	if limit > 0 {
+		r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, limit)
+	}
+	return r
+}
@@ -10,7 +10,8 @@ type OK struct {
 	Status string `json:"status"`
 }
 
-// Respond converts a Go value to JSON andd send it to the client.
+// Respond converts a Go value to JSON andd send it to the client. Successful GET and HEAD
+// responses carry an ETag and are responded to with a 304 when the client has a fresh copy.
 func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statusCode int) error {
 
 	// Set the status code for the request logger middleware.
@@ -29,8 +30,19 @@ func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statu
 		return err
 	}
 
+	// A conditional request for a representation the client already has is responded to
+	// with just the headers.
+	if statusCode == http.StatusOK && notModified(ctx, w.Header(), jsonData) {
+		SetStatusCode(ctx, http.StatusNotModified)
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
 	// Set the content type and headers once we know marshaling is successful.
-	w.Header().Set("Content-Type", "application/json")
+	// A content type already set by the caller (ie: application/problem+json) is respected.
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
 
 	// Write the status code to the response.
 	w.WriteHeader(statusCode)
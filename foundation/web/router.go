@@ -0,0 +1,170 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+/**
+router is the behaviour required of the mux backing an App. Keeping the mux behind this interface stops
+its API from leaking into our handlers and allows the mux to be swapped by providing an option to NewApp.
+Paths are registered using the {name} syntax for parameters which is supported by every adapter.
+*/
+type router interface {
+	http.Handler
+
+	// handle registers the handler for requests matching the method and path.
+	handle(method string, path string, handler http.Handler)
+
+	// param returns the value of the named path parameter for a routed request.
+	param(r *http.Request, key string) string
+
+	// notFound registers the handler for requests which do not match any path.
+	notFound(handler http.Handler)
+
+	// methodNotAllowed registers the handler for requests matching a path but not a method.
+	methodNotAllowed(handler http.Handler)
+
+	// allowed returns the methods registered for the path of the request.
+	allowed(r *http.Request) []string
+}
+
+// WithGorillaMux backs the App with gorilla/mux. This is the default router.
+func WithGorillaMux() Option {
+	return func(a *App) {
+		a.router = newGorillaRouter()
+	}
+}
+
+// WithServeMux backs the App with the net/http ServeMux using the method and wildcard patterns
+// introduced in Go 1.22.
+func WithServeMux() Option {
+	return func(a *App) {
+		a.router = newServeMuxRouter()
+	}
+}
+
+// =============================================================================
+
+// gorillaRouter adapts gorilla/mux to the router interface.
+type gorillaRouter struct {
+	*mux.Router
+}
+
+func newGorillaRouter() *gorillaRouter {
+	return &gorillaRouter{mux.NewRouter()}
+}
+
+func (gr *gorillaRouter) handle(method string, path string, handler http.Handler) {
+	gr.Handle(path, handler).Methods(method)
+}
+
+func (gr *gorillaRouter) param(r *http.Request, key string) string {
+	return mux.Vars(r)[key]
+}
+
+func (gr *gorillaRouter) notFound(handler http.Handler) {
+	gr.NotFoundHandler = handler
+}
+
+func (gr *gorillaRouter) methodNotAllowed(handler http.Handler) {
+	gr.MethodNotAllowedHandler = handler
+}
+
+func (gr *gorillaRouter) allowed(r *http.Request) []string {
+	var methods []string
+	gr.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		routeMethods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		// Match the request against the route as if it was made with each of the route methods.
+		for _, method := range routeMethods {
+			req := *r
+			req.Method = method
+
+			var match mux.RouteMatch
+			if route.Match(&req, &match) {
+				methods = append(methods, method)
+			}
+		}
+		return nil
+	})
+	return methods
+}
+
+// =============================================================================
+
+/**
+serveMuxRouter adapts the net/http ServeMux to the router interface. The ServeMux has no hooks for
+unmatched requests so ServeHTTP checks for a matching pattern before dispatching the request.
+*/
+type serveMuxRouter struct {
+	mux                     *http.ServeMux
+	methods                 []string
+	notFoundHandler         http.Handler
+	methodNotAllowedHandler http.Handler
+}
+
+func newServeMuxRouter() *serveMuxRouter {
+	return &serveMuxRouter{
+		mux:                     http.NewServeMux(),
+		notFoundHandler:         http.NotFoundHandler(),
+		methodNotAllowedHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}),
+	}
+}
+
+func (sr *serveMuxRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := sr.mux.Handler(r); pattern == "" {
+		if len(sr.allowed(r)) > 0 {
+			sr.methodNotAllowedHandler.ServeHTTP(w, r)
+			return
+		}
+		sr.notFoundHandler.ServeHTTP(w, r)
+		return
+	}
+
+	sr.mux.ServeHTTP(w, r)
+}
+
+func (sr *serveMuxRouter) handle(method string, path string, handler http.Handler) {
+	sr.mux.Handle(method+" "+path, handler)
+
+	for _, m := range sr.methods {
+		if m == method {
+			return
+		}
+	}
+	sr.methods = append(sr.methods, method)
+}
+
+func (sr *serveMuxRouter) param(r *http.Request, key string) string {
+	return r.PathValue(key)
+}
+
+func (sr *serveMuxRouter) notFound(handler http.Handler) {
+	sr.notFoundHandler = handler
+}
+
+func (sr *serveMuxRouter) methodNotAllowed(handler http.Handler) {
+	sr.methodNotAllowedHandler = handler
+}
+
+func (sr *serveMuxRouter) allowed(r *http.Request) []string {
+	var methods []string
+
+	// Match the request against the mux as if it was made with each of the registered methods.
+	for _, method := range sr.methods {
+		req := *r
+		req.Method = method
+
+		if _, pattern := sr.mux.Handler(&req); pattern != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
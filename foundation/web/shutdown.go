@@ -1,6 +1,11 @@
 package web
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
 
 /**
 shutdownError is a type used to help with the graceful termination of the
@@ -31,3 +36,80 @@ func IsShutdown(err error) bool {
 	var se *shutdownError
 	return errors.As(err, &se)
 }
+
+// integrityError is a failure which puts the integrity of the service in doubt without
+// requiring an immediate shutdown.
+type integrityError struct {
+	err error
+}
+
+// Error is the implementation of the error interface.
+func (ie *integrityError) Error() string {
+	return ie.err.Error()
+}
+
+// Unwrap returns the failure.
+func (ie *integrityError) Unwrap() error {
+	return ie.err
+}
+
+// NewIntegrityError returns an error which counts towards the threshold of the ShutdownPolicy.
+func NewIntegrityError(err error) error {
+	return &integrityError{err}
+}
+
+// IsIntegrity checks to see if an integrity error is contained in the specified error value.
+// Cancelled requests are never integrity failures since the client went away.
+func IsIntegrity(err error) bool {
+	var ie *integrityError
+	return errors.As(err, &ie) && !errors.Is(err, context.Canceled)
+}
+
+/**
+ShutdownPolicy decides how the App reacts to an error escaping the middleware chain. Shutdown errors always
+signal a shutdown. Every other error is reported to OnError, but only integrity errors signal a shutdown and
+only once Threshold of them have occurred within Window. Other errors, such as failing to write the response
+to a client which disconnected, never signal a shutdown. A Threshold of 0 never signals a shutdown for
+integrity errors and a Window of 0 counts them for the life of the App.
+*/
+type ShutdownPolicy struct {
+	Threshold int
+	Window    time.Duration
+	OnError   func(ctx context.Context, err error)
+}
+
+// circuit tracks integrity failures to decide when the threshold of the policy has been reached.
+type circuit struct {
+	mu       sync.Mutex
+	policy   ShutdownPolicy
+	failures []time.Time
+}
+
+// trip records a failure and reports whether the threshold has been reached. The failures are
+// reset once the circuit trips.
+func (c *circuit) trip(now time.Time) bool {
+	if c.policy.Threshold <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Forget the failures which happened outside of the window.
+	if c.policy.Window > 0 {
+		cutoff := now.Add(-c.policy.Window)
+		i := 0
+		for i < len(c.failures) && !c.failures[i].After(cutoff) {
+			i++
+		}
+		c.failures = c.failures[i:]
+	}
+
+	c.failures = append(c.failures, now)
+	if len(c.failures) < c.policy.Threshold {
+		return false
+	}
+
+	c.failures = nil
+	return true
+}
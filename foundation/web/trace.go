@@ -0,0 +1,53 @@
+package web
+
+import "net/http"
+
+// RequestIDHeader is the header carrying the trace id between services.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen is the longest inbound request id which is honoured.
+const maxRequestIDLen = 128
+
+// validRequestID reports whether an inbound request id can be used as the trace id. Only
+// a limited set of characters is allowed so the id is safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Transport is an http.RoundTripper which adds the trace id of the request context to
+// outbound requests so the logs correlate across our services. Use it as the Transport
+// of the http.Client calling other services and provide the handler context to the request.
+type Transport struct {
+
+	// Base is the RoundTripper making the request, http.DefaultTransport is used when nil.
+	Base http.RoundTripper
+}
+
+// RoundTrip adds the trace id header when the request does not already carry one.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if v, ok := r.Context().Value(key).(*Values); ok && r.Header.Get(RequestIDHeader) == "" {
+
+		// A RoundTripper must not modify the request it was given.
+		r = r.Clone(r.Context())
+		r.Header.Set(RequestIDHeader, v.TracedID)
+	}
+
+	return base.RoundTrip(r)
+}
@@ -6,16 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"syscall"
 	"time"
 
-	/**
-	  Currently we are embedding gorilla/mux as the main mux and hiding this away through the App struct.
-	  This gives us the ability to easily switch over the mux by just changing the embedding
-	  and some minor api logic in the foundation/web layer.
-	*/
 	"github.com/google/uuid"
-	"github.com/gorilla/mux"
 )
 
 // A Handler is a type that handles an http request within our App.
@@ -27,32 +22,71 @@ object for each of our http handlers. Feel free to add any configuration
 data/logic on this App struct.
 */
 type App struct {
-	*mux.Router
-	shutdown chan os.Signal
-	mw       []Middleware
+	router       router
+	shutdown     chan os.Signal
+	mw           []Middleware
+	maxBodyBytes int64
+	circuit      circuit
+	paths        map[string]bool
+}
+
+// Option configures optional behaviour of an App.
+type Option func(a *App)
+
+// WithMaxBodyBytes limits the size of request bodies. Reading past the limit fails and
+// Decode responds with a 413. A limit of 0 disables the check.
+func WithMaxBodyBytes(n int64) Option {
+	return func(a *App) {
+		a.maxBodyBytes = n
+	}
+}
+
+// WithShutdownPolicy sets the policy deciding when errors escaping the middleware chain signal
+// a shutdown. By default only shutdown errors signal a shutdown.
+func WithShutdownPolicy(policy ShutdownPolicy) Option {
+	return func(a *App) {
+		a.circuit.policy = policy
+	}
 }
 
 // NewApp creates an App vaue that handles a set of routes for the application.
-func NewApp(shutdown chan os.Signal, mw ...Middleware) *App {
-	return &App{
-		mux.NewRouter(),
-		shutdown,
-		mw,
+func NewApp(shutdown chan os.Signal, mw []Middleware, opts ...Option) *App {
+	a := App{
+		router:       newGorillaRouter(),
+		shutdown:     shutdown,
+		mw:           mw,
+		maxBodyBytes: DefaultMaxBodyBytes,
+		paths:        make(map[string]bool),
 	}
+
+	for _, opt := range opts {
+		opt(&a)
+	}
+
+	// Requests the router can't match are still processed through the app specific middleware
+	// so they are logged, measured and responded to like any other error.
+	a.router.notFound(a.serve("", wrapMiddleWare(a.mw, a.notFound)))
+	a.router.methodNotAllowed(a.serve("", wrapMiddleWare(a.mw, a.methodNotAllowed)))
+
+	return &a
+}
+
+// ServeHTTP implements the http.Handler interface by dispatching the request to the router.
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.router.ServeHTTP(w, r)
 }
 
 // SignalShutdown is used to gracefully shutdown the app when an integrity issue is identified.
+// The signal is dropped if a shutdown is already pending so handlers never block on it.
 func (a *App) SignalShutdown() {
-	a.shutdown <- syscall.SIGTERM
+	select {
+	case a.shutdown <- syscall.SIGTERM:
+	default:
+	}
 }
 
 // handleReq is the main method we use to build are App based networking handlers.
-func (a *App) handleReq(path string, group string, httpMethod string, handler Handler, mw ...Middleware) {
-	p := path
-	if group != "" {
-		p = fmt.Sprintf("/%s%s", group, path)
-	}
-
+func (a *App) handleReq(path string, httpMethod string, handler Handler, mw ...Middleware) {
 	/**
 	First wrap handler specific middleware.
 	Second add the app specific middleware after, Which results in the app specific middleware being executed
@@ -61,65 +95,151 @@ func (a *App) handleReq(path string, group string, httpMethod string, handler Ha
 	handler = wrapMiddleWare(mw, handler)
 	handler = wrapMiddleWare(a.mw, handler)
 
-	a.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+	a.router.handle(httpMethod, path, a.serve(path, handler))
+
+	// Every path responds to OPTIONS so browsers can make CORS preflight requests. Only the app
+	// specific middleware is executed since preflight requests carry no credentials.
+	if !a.paths[path] {
+		a.paths[path] = true
+		a.router.handle(http.MethodOptions, path, a.serve(path, wrapMiddleWare(a.mw, a.options)))
+	}
+}
+
+// serve converts a Handler into an http.Handler which sets up the request values prior to
+// calling the Handler. The route is the path template the Handler was registered with.
+func (a *App) serve(route string, handler Handler) http.Handler {
+	h := func(w http.ResponseWriter, r *http.Request) {
 
 		// PRE CODE PROCESSING
 		ctx := r.Context()
 
+		if a.maxBodyBytes > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, a.maxBodyBytes)
+		}
+
+		// Honour the request id of the caller so the logs of our services correlate, and
+		// echo it back so the caller can correlate the response.
+		traceID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(traceID) {
+			traceID = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, traceID)
+
 		v := Values{
-			TracedID: uuid.New().String(),
+			TracedID: traceID,
 			Now:      time.Now(),
+			Route:    route,
 		}
 
 		ctx = context.WithValue(ctx, key, &v)
 
+		// Provide the router to the request so Param can look up path parameters.
+		ctx = context.WithValue(ctx, routerKey, a.router)
+
+		// Provide the validators of a conditional request to Respond.
+		ctx = context.WithValue(ctx, conditionsKey, newConditions(r))
+		r = r.WithContext(ctx)
+
 		// Call the wrapped handler.
+		// The Error should not reach the outer most handler. If it does it means we have an issue with our system.
 		if err := handler(ctx, w, r); err != nil {
-			// The Error should not reach the outer most handler. If it does it means we have an issue with our system.
-			// In this case signal shutdown.
-			a.SignalShutdown()
+			a.handleErr(ctx, err)
 		}
 
 		// POST CODE PROCESSING
-	}).Methods(httpMethod)
+	}
+
+	return http.HandlerFunc(h)
+}
+
+// handleErr applies the shutdown policy to an error which escaped the middleware chain.
+func (a *App) handleErr(ctx context.Context, err error) {
+	if IsShutdown(err) {
+		a.SignalShutdown()
+		return
+	}
+
+	if a.circuit.policy.OnError != nil {
+		a.circuit.policy.OnError(ctx, err)
+	}
+
+	if IsIntegrity(err) && a.circuit.trip(time.Now()) {
+		a.SignalShutdown()
+	}
+}
+
+// notFound is the Handler for requests which do not match a route.
+func (a *App) notFound(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return NewError(fmt.Errorf("no route found for path %s", r.URL.Path), http.StatusNotFound)
+}
+
+// methodNotAllowed is the Handler for requests matching a route with a different method. The
+// Allow header is set to the methods which are supported.
+func (a *App) methodNotAllowed(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Allow", a.allowed(r))
+	return NewError(fmt.Errorf("method %s not allowed for path %s", r.Method, r.URL.Path), http.StatusMethodNotAllowed)
+}
+
+// options is the Handler for OPTIONS requests to a registered path.
+func (a *App) options(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Allow", a.allowed(r))
+	return Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// allowed returns the Allow header value for the path of the request. OPTIONS is listed last
+// since it is registered for every path.
+func (a *App) allowed(r *http.Request) string {
+	var methods []string
+	var options bool
+	for _, method := range a.router.allowed(r) {
+		if method == http.MethodOptions {
+			options = true
+			continue
+		}
+		methods = append(methods, method)
+	}
+	if options {
+		methods = append(methods, http.MethodOptions)
+	}
+	return strings.Join(methods, ", ")
 }
 
 /**
 Get handler for handling all http GET requests.
 Calls to this handler are used for Reading data.
 */
-func (a *App) Get(path string, group string, handler Handler, mw ...Middleware) {
-	a.handleReq(path, group, http.MethodGet, handler, mw...)
+func (a *App) Get(path string, handler Handler, mw ...Middleware) {
+	a.handleReq(path, http.MethodGet, handler, mw...)
 }
 
 /**
 Post handler for handling all http POST requests.
 Calls to this handler are used for writing data.
 */
-func (a *App) Post(path string, group string, handler Handler, mw ...Middleware) {
-	a.handleReq(path, group, http.MethodPost, handler, mw...)
+func (a *App) Post(path string, handler Handler, mw ...Middleware) {
+	a.handleReq(path, http.MethodPost, handler, mw...)
 }
 
 /**
 Patch handler for handling all http PATCH requests.
 Calls to this handler are used to update/modify an existing resource.
 */
-func (a *App) Patch(path string, group string, handler Handler, mw ...Middleware) {
-	a.handleReq(path, group, http.MethodPatch, handler, mw...)
+func (a *App) Patch(path string, handler Handler, mw ...Middleware) {
+	a.handleReq(path, http.MethodPatch, handler, mw...)
 }
 
 /**
 Put handler for handling all http PUT requests.
 Calls to this handler are used for replacing/overriding an existing resource.
 */
-func (a *App) Put(path string, group string, handler Handler, mw ...Middleware) {
-	a.handleReq(path, group, http.MethodPut, handler, mw...)
+func (a *App) Put(path string, handler Handler, mw ...Middleware) {
+	a.handleReq(path, http.MethodPut, handler, mw...)
 }
 
 /**
 Delete handler for handling all http DELETE requests.
 Calls to this handler are used for deleting a resource.
 */
-func (a *App) Delete(path string, group string, handler Handler, mw ...Middleware) {
-	a.handleReq(path, group, http.MethodDelete, handler, mw...)
+func (a *App) Delete(path string, handler Handler, mw ...Middleware) {
+	a.handleReq(path, http.MethodDelete, handler, mw...)
 }
@@ -0,0 +1,410 @@
+package web_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdforte/go-service/foundation/logger"
+	"github.com/rdforte/go-service/foundation/web"
+)
+
+func TestGroup(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Mounting routes within groups")
+	{
+		tl.It("should nest prefixes and run middleware from the outer most to the inner most")
+		{
+			var calls []string
+
+			app := web.NewApp(make(chan os.Signal, 1), []web.Middleware{trace(&calls, "app")})
+
+			v1 := app.Group("v1", trace(&calls, "v1"))
+			admin := v1.Group("/admin/", trace(&calls, "admin"))
+
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				calls = append(calls, "handler")
+				return web.RespondOk(ctx, w)
+			}
+
+			v1.Get("/user", handler)
+			admin.Get("/user", handler, trace(&calls, "route"))
+
+			tests := map[string]string{
+				"/v1/user":       "app,v1,handler",
+				"/v1/admin/user": "app,v1,admin,route,handler",
+			}
+
+			for path, exp := range tests {
+				calls = nil
+
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+				if w.Code != http.StatusOK {
+					tl.Failed("Should route to the handler", fmt.Errorf("path[%s] status[%d]", path, w.Code))
+				}
+				if got := strings.Join(calls, ","); got != exp {
+					tl.Failed("Should run the middleware in order", fmt.Errorf("path[%s] exp[%s] got[%s]", path, exp, got))
+				}
+			}
+			tl.Success("Should route to the handler and run the middleware in order")
+		}
+	}
+}
+
+// trace returns a middleware recording its name when executed.
+func trace(calls *[]string, name string) web.Middleware {
+	return func(handler web.Handler) web.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			*calls = append(*calls, name)
+			return handler(ctx, w, r)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Backing the App with different routers")
+	{
+		routers := map[string]web.Option{
+			"gorilla/mux":  web.WithGorillaMux(),
+			"net/http mux": web.WithServeMux(),
+		}
+
+		for name, opt := range routers {
+			tl.It(fmt.Sprintf("should route requests and provide path parameters using %s", name))
+			{
+				app := web.NewApp(make(chan os.Signal, 1), nil, opt)
+
+				var got string
+				app.Group("v1").Get("/user/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					got = web.Param(r, "id")
+					return web.RespondOk(ctx, w)
+				})
+
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/user/42", nil))
+
+				if w.Code != http.StatusOK {
+					tl.Failed("Should route to the handler", fmt.Errorf("status[%d]", w.Code))
+				}
+				tl.Success("Should route to the handler")
+
+				if got != "42" {
+					tl.Failed("Should provide the path parameter", fmt.Errorf("id[%s]", got))
+				}
+				tl.Success("Should provide the path parameter")
+			}
+		}
+	}
+}
+
+func TestUnmatched(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Handling requests which do not match a route")
+	{
+		routers := map[string]web.Option{
+			"gorilla/mux":  web.WithGorillaMux(),
+			"net/http mux": web.WithServeMux(),
+		}
+
+		for name, opt := range routers {
+			tl.It(fmt.Sprintf("should respond through the app middleware using %s", name))
+			{
+				var calls []string
+
+				app := web.NewApp(make(chan os.Signal, 1), []web.Middleware{trace(&calls, "app"), respondError()}, opt)
+
+				handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return web.RespondOk(ctx, w)
+				}
+				app.Get("/user", handler)
+				app.Delete("/user", handler)
+
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+				if w.Code != http.StatusNotFound || strings.Join(calls, ",") != "app" {
+					tl.Failed("Should respond with a 404", fmt.Errorf("status[%d] calls%v", w.Code, calls))
+				}
+				tl.Success("Should respond with a 404")
+
+				w = httptest.NewRecorder()
+				app.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/user", nil))
+
+				if w.Code != http.StatusMethodNotAllowed {
+					tl.Failed("Should respond with a 405", fmt.Errorf("status[%d]", w.Code))
+				}
+				tl.Success("Should respond with a 405")
+
+				if allow := w.Header().Get("Allow"); allow != "GET, DELETE, OPTIONS" {
+					tl.Failed("Should set the Allow header", fmt.Errorf("allow[%s]", allow))
+				}
+				tl.Success("Should set the Allow header")
+
+				w = httptest.NewRecorder()
+				app.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/user", nil))
+
+				if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, DELETE, OPTIONS" {
+					tl.Failed("Should respond to OPTIONS", fmt.Errorf("status[%d] allow[%s]", w.Code, w.Header().Get("Allow")))
+				}
+				tl.Success("Should respond to OPTIONS")
+			}
+		}
+	}
+}
+
+func TestShutdownPolicy(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Handling errors which escape the middleware chain")
+	{
+		tl.It("should only signal a shutdown once the threshold is reached")
+		{
+			shutdown := make(chan os.Signal, 1)
+			var reported int
+
+			app := web.NewApp(shutdown, nil, web.WithShutdownPolicy(web.ShutdownPolicy{
+				Threshold: 3,
+				Window:    time.Minute,
+				OnError: func(ctx context.Context, err error) {
+					reported++
+				},
+			}))
+
+			app.Get("/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.NewIntegrityError(errors.New("corrupted state"))
+			})
+
+			for i := 0; i < 2; i++ {
+				app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+			}
+
+			if reported != 2 || len(shutdown) != 0 {
+				tl.Failed("Should report the errors without a shutdown", fmt.Errorf("reported[%d] signals[%d]", reported, len(shutdown)))
+			}
+			tl.Success("Should report the errors without a shutdown")
+
+			app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+			if len(shutdown) != 1 {
+				tl.Failed("Should signal a shutdown at the threshold", fmt.Errorf("signals[%d]", len(shutdown)))
+			}
+			tl.Success("Should signal a shutdown at the threshold")
+		}
+
+		tl.It("should never signal a shutdown for errors which are not integrity errors")
+		{
+			shutdown := make(chan os.Signal, 1)
+			var reported int
+
+			app := web.NewApp(shutdown, nil, web.WithShutdownPolicy(web.ShutdownPolicy{
+				Threshold: 1,
+				OnError: func(ctx context.Context, err error) {
+					reported++
+				},
+			}))
+
+			app.Get("/disconnect", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return errors.New("write: broken pipe")
+			})
+			app.Get("/cancel", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.NewIntegrityError(fmt.Errorf("querying: %w", context.Canceled))
+			})
+
+			for _, path := range []string{"/disconnect", "/cancel"} {
+				app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			}
+
+			if reported != 2 || len(shutdown) != 0 {
+				tl.Failed("Should report the errors without a shutdown", fmt.Errorf("reported[%d] signals[%d]", reported, len(shutdown)))
+			}
+			tl.Success("Should report the errors without a shutdown")
+		}
+
+		tl.It("should signal a shutdown for a shutdown error")
+		{
+			shutdown := make(chan os.Signal, 1)
+
+			app := web.NewApp(shutdown, nil)
+			app.Get("/shutdown", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.NewShutdownError("integrity issue")
+			})
+
+			app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/shutdown", nil))
+
+			if len(shutdown) != 1 {
+				tl.Failed("Should signal a shutdown", fmt.Errorf("signals[%d]", len(shutdown)))
+			}
+			tl.Success("Should signal a shutdown")
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	tl.Describe("Propagating the request id")
+	{
+		tl.It("should honour a valid inbound request id and pass it on to outbound requests")
+		{
+			// The downstream service records the request id it receives.
+			var outbound string
+			downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				outbound = r.Header.Get(web.RequestIDHeader)
+			}))
+			defer downstream.Close()
+
+			client := http.Client{Transport: &web.Transport{}}
+
+			app := web.NewApp(make(chan os.Signal, 1), nil)
+			app.Get("/user", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, downstream.URL, nil)
+				if err != nil {
+					return err
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					return err
+				}
+				resp.Body.Close()
+				return web.RespondOk(ctx, w)
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/user", nil)
+			r.Header.Set(web.RequestIDHeader, "req-123")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if id := w.Header().Get(web.RequestIDHeader); id != "req-123" {
+				tl.Failed("Should echo the request id", fmt.Errorf("id[%s]", id))
+			}
+			tl.Success("Should echo the request id")
+
+			if outbound != "req-123" {
+				tl.Failed("Should add the request id to outbound requests", fmt.Errorf("id[%s]", outbound))
+			}
+			tl.Success("Should add the request id to outbound requests")
+
+			r = httptest.NewRequest(http.MethodGet, "/user", nil)
+			r.Header.Set(web.RequestIDHeader, "bad id\n")
+			w = httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if id := w.Header().Get(web.RequestIDHeader); id == "" || id == "bad id\n" {
+				tl.Failed("Should replace an invalid request id", fmt.Errorf("id[%q]", id))
+			}
+			tl.Success("Should replace an invalid request id")
+		}
+	}
+}
+
+// respondError returns a middleware responding with the status of a web.Error.
+func respondError() web.Middleware {
+	return func(handler web.Handler) web.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if err := handler(ctx, w, r); err != nil {
+				var webErr *web.Error
+				if !errors.As(err, &webErr) {
+					return err
+				}
+				return web.Respond(ctx, w, webErr.Error(), webErr.Status)
+			}
+			return nil
+		}
+	}
+}
+
+func TestConditional(t *testing.T) {
+	tl := logger.NewTestLog(t)
+
+	modified := time.Date(2022, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	app := web.NewApp(make(chan os.Signal, 1), nil)
+	app.Get("/hash", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, map[string]string{"name": "bill"}, http.StatusOK)
+	})
+	app.Get("/version", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		web.SetETag(w, "v2")
+		web.SetLastModified(w, modified)
+		return web.Respond(ctx, w, map[string]string{"name": "bill"}, http.StatusOK)
+	})
+	app.Patch("/version", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, web.IfMatch(r), http.StatusOK)
+	})
+
+	serve := func(method string, path string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+
+	tl.Describe("Responding to conditional requests")
+	{
+		tl.It("should hash the body into an ETag and respond 304 when it matches")
+		{
+			w := serve(http.MethodGet, "/hash", nil)
+			etag := w.Header().Get("ETag")
+			if w.Code != http.StatusOK || etag == "" {
+				tl.Failed("Should set an ETag", fmt.Errorf("status[%d] etag[%s]", w.Code, etag))
+			}
+			tl.Success("Should set an ETag")
+
+			w = serve(http.MethodGet, "/hash", map[string]string{"If-None-Match": `"other", W/` + etag})
+			if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+				tl.Failed("Should respond 304 without a body", fmt.Errorf("status[%d] body[%s]", w.Code, w.Body))
+			}
+			tl.Success("Should respond 304 without a body")
+		}
+
+		tl.It("should use the version and Last-Modified set by the handler")
+		{
+			w := serve(http.MethodGet, "/version", map[string]string{"If-None-Match": `"v1"`})
+			if w.Code != http.StatusOK || w.Header().Get("ETag") != `"v2"` {
+				tl.Failed("Should respond with the current version", fmt.Errorf("status[%d] etag[%s]", w.Code, w.Header().Get("ETag")))
+			}
+			tl.Success("Should respond with the current version")
+
+			w = serve(http.MethodGet, "/version", map[string]string{"If-None-Match": web.CodingETag(`"v2"`, "gzip")})
+			if w.Code != http.StatusNotModified {
+				tl.Failed("Should respond 304 for the ETag of the encoded version", fmt.Errorf("status[%d]", w.Code))
+			}
+			tl.Success("Should respond 304 for the ETag of the encoded version")
+
+			w = serve(http.MethodGet, "/version", map[string]string{"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat)})
+			if w.Code != http.StatusNotModified {
+				tl.Failed("Should respond 304 when not modified since", fmt.Errorf("status[%d]", w.Code))
+			}
+			tl.Success("Should respond 304 when not modified since")
+
+			w = serve(http.MethodGet, "/version", map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)})
+			if w.Code != http.StatusOK {
+				tl.Failed("Should respond 200 when modified since", fmt.Errorf("status[%d]", w.Code))
+			}
+			tl.Success("Should respond 200 when modified since")
+		}
+
+		tl.It("should return the versions of the If-Match header")
+		{
+			w := serve(http.MethodPatch, "/version", map[string]string{"If-Match": `"v1", W/"v2", "v3-gzip"`})
+			if body := strings.TrimSpace(w.Body.String()); body != `["v1","W/\"v2\"","v3"]` || w.Header().Get("ETag") != "" {
+				tl.Failed("Should return the versions", fmt.Errorf("body[%s] etag[%s]", body, w.Header().Get("ETag")))
+			}
+			tl.Success("Should return the versions")
+		}
+	}
+}
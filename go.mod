@@ -1,6 +1,6 @@
 module github.com/rdforte/go-service
 
-go 1.17
+go 1.22
 
 require (
 	go.uber.org/automaxprocs v1.4.0
@@ -13,7 +13,6 @@ require github.com/gorilla/mux v1.8.0
 
 require (
 	github.com/ardanlabs/darwin v1.3.0
-	github.com/ardanlabs/service v0.0.0-20220211204342-e3cd11bff3a5
 	github.com/fsnotify/fsnotify v1.5.1 // indirect
 	github.com/go-playground/locales v0.14.0
 	github.com/go-playground/universal-translator v0.18.0
@@ -41,4 +40,7 @@ require (
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
 
-require github.com/leodido/go-urn v1.2.1 // indirect
+require (
+	github.com/leodido/go-urn v1.2.1 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+)